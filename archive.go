@@ -0,0 +1,115 @@
+package find
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// isArchive reports if file is a zip archive by its name.
+func isArchive(name string) bool {
+	return strings.EqualFold(path.Ext(name), ".zip")
+}
+
+// searchArchive matches entries of the zip archive for [WithArchives].
+// Entries are treated as they were in the folder of the archive.
+func (o *options) searchArchive(ts Templates, d *dir, e *entry) error {
+	info, err := e.Info()
+	if err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := o.fsys.Open(e.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Files of fs.FS might not support random access.
+	ra, ok := f.(io.ReaderAt)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+
+		ra = bytes.NewReader(data)
+	}
+
+	zr, err := zip.NewReader(ra, info.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, zf := range zr.File {
+		if o.limitReached() {
+			return nil
+		}
+
+		// Names with '..' must not escape the archive.
+		name := strings.TrimSuffix(zf.Name, "/")
+		if !fs.ValidPath(name) || name == "." {
+			continue
+		}
+
+		ae := &entry{
+			DirEntry: fs.FileInfoToDirEntry(zf.FileInfo()),
+			path:     o.fsys.Join(e.path, name),
+		}
+
+		ok, err := o.matchArchived(ts, d, ae)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			// Archive is not traversed further the same way
+			// as the folder.
+			if err := o.emitEntry(ts, ae); err != nil {
+				return skipDir(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchArchived acts the same way as matchEntry, but for the entry of
+// the archive, which cannot be read from the file system.
+func (o *options) matchArchived(ts Templates, d *dir, e *entry) (bool, error) {
+	if o.content != nil || o.types != nil || o.empty || o.broken {
+		return false, nil
+	}
+
+	isDir := e.IsDir()
+
+	if !o.atDepth(d.depth) ||
+		!o.isSearchedType(e, isDir) ||
+		!o.match(ts, e.path) {
+		return false, nil
+	}
+
+	if ok, err := o.userFilter(e); !ok || err != nil {
+		return false, err
+	}
+
+	if _, err := e.Info(); err != nil {
+		return false, err
+	}
+
+	ok, err := o.filter(e, isDir)
+	if !ok || err != nil || o.tmplLimit <= 0 || o.invert {
+		return ok, err
+	}
+
+	e.tmpl = o.takeTemplate(ts, e.path)
+
+	return e.tmpl != nil, nil
+}
@@ -0,0 +1,91 @@
+package find
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// zipFile creates zip archive in memory with the given files.
+func zipFile(t *testing.T, files ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+	for _, name := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Folders have no content.
+		if strings.HasSuffix(name, "/") {
+			continue
+		}
+
+		if _, err := w.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestWithArchives(t *testing.T) {
+	root := makeTree(t, "sub/", "main.go")
+
+	data := zipFile(t, "docs/", "docs/readme.txt", "util.go", "../evil.go")
+	if err := os.WriteFile(filepath.Join(root, "sub", "src.zip"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := mustFind(t, root, "*.go", Recursively)
+	assertPaths(t, got, "main.go")
+
+	got = mustFind(t, root, "*.go", Recursively, WithArchives)
+	assertPaths(t, got, "main.go", "sub/src.zip/util.go")
+
+	got = mustFind(t, root, "*", Recursively, WithArchives, Only(Folder))
+	assertPaths(t, got, "sub", "sub/src.zip/docs")
+
+	got = mustFind(t, root, "*src.zip/docs/*", Recursively, WithArchives, MatchFullPath)
+	assertPaths(t, got, "sub/src.zip/docs/readme.txt")
+
+	// Archives in fs.FS are read the same way.
+	fsys := fstest.MapFS{"a/src.zip": {Data: data}}
+
+	res, err := FindFS(context.Background(), fsys, ".", "*.txt", Recursively, WithArchives)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, "a/src.zip/docs/readme.txt")
+}
+
+func TestWithArchives_corrupt(t *testing.T) {
+	root := makeTree(t, "1.txt")
+
+	err := os.WriteFile(filepath.Join(root, "bad.zip"), []byte("not a zip"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Find(context.Background(), root, "*.txt", WithArchives)
+	if !errors.Is(err, zip.ErrFormat) {
+		t.Errorf("want %v, got: %v", zip.ErrFormat, err)
+	}
+
+	got := mustFind(t, root, "*.txt", WithArchives, WithErrorsSkip)
+	assertPaths(t, got, "1.txt")
+}
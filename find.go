@@ -5,12 +5,45 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
-var ErrTemplateType = errors.New("cannot define type of the template")
+var (
+	ErrTemplateType = errors.New("cannot define type of the template")
+	ErrSymlinkLoop  = errors.New("symlink loop")
+
+	// ErrStopWalk can be returned from [WithVisitor] callback
+	// to stop the search without an error.
+	ErrStopWalk = errors.New("stop walk")
+
+	// ErrSkipDir can be returned from [WithErrorHandler] callback
+	// to skip the rest of the folder, where the error occurred, or
+	// from [WithVisitor] callback to not traverse the matched folder.
+	ErrSkipDir = errors.New("skip dir")
+
+	// ErrRootNotFound is returned if the searched root does not exist.
+	ErrRootNotFound = errors.New("root not found")
+
+	// ErrInvalidRoot is returned if the searched root cannot be
+	// resolved for any other reason e.g., permission denied.
+	ErrInvalidRoot = errors.New("invalid root")
+
+	// ErrWalk wraps critical errors, which occurred during the walk
+	// e.g., if subfolder cannot be read.
+	ErrWalk = errors.New("walk")
+
+	// ErrScanLimit is returned if search inspected more entries
+	// than [MaxScanned] allows.
+	ErrScanLimit = errors.New("scan limit reached")
+
+	// ErrNegativeLimit is returned by [FindN] if limit is below zero.
+	ErrNegativeLimit = errors.New("negative limit")
+)
 
 // Templater defines type constraint for generic Find function.
 type Templater interface {
@@ -18,9 +51,12 @@ type Templater interface {
 }
 
 // FindWithIterator acts the same way as [Find] but returns channels instead.
-// String channel will return every match. Error channel returns first occured
-// error during search or if [WithErrorsSkip] was set, first critical error.
-// As soon as search is over or interrupted, both channels will be closed.
+// String channel will return every match. Error channel returns at most one
+// error: the first occured error during search or if [WithErrorsSkip] was set,
+// all skipped errors and the critical one, if any, joined with [errors.Join].
+// Skipped errors are sent only after the search is over, while the critical
+// one stops it. As soon as search is over or interrupted, both channels will
+// be closed.
 // For example:
 //
 //	outCh, errCh := FindWithIterator(ctx, where, ts, opts...)
@@ -41,6 +77,7 @@ func FindWithIterator[T Templater](
 	opt.iterCh = make(chan string, opt.maxIter)
 	opt.errCh = make(chan error, 1)
 	opt.iter = true
+	opt.keepErrs = true
 
 	go func() {
 		defer func() {
@@ -48,168 +85,1043 @@ func FindWithIterator[T Templater](
 			close(opt.errCh)
 		}()
 
-		resPath, err := resolvePath(where)
-		if err != nil {
+		err := run(ctx, where, t, opt)
+		if err = errors.Join(append(opt.skipped, err)...); err != nil {
 			opt.errCh <- err
-			return
 		}
+	}()
 
-		opt.orig = where
-		opt.resOrig = resPath
+	return opt.iterCh, opt.errCh
+}
+
+// Find searches for matches with the given templates in where.
+// If where is a file, it is matched with templates itself.
+// If ctx is done before the search is over, paths found so far
+// are returned alongside the context error.
+func Find[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	opts ...optFunc,
+) ([]string, error) {
+	opt := defaultOptionsWithCustom(opts...)
+
+	if err := run(ctx, where, t, opt); err != nil {
+		if interrupted(err) {
+			return opt.paths(), err
+		}
+
+		return nil, err
+	}
+
+	return opt.paths(), opt.collected()
+}
+
+// FindTemplates acts the same way as [Find], but uses already compiled
+// templates, so they can be reused in several searches.
+//
+// Note: search is case sensitive by default on all platforms. With
+// [Insensitive] or [WithCaseFunc] copies of the templates are normalized
+// the same way as the matched names, templates themselves are not changed.
+func FindTemplates(
+	ctx context.Context,
+	where string,
+	ts Templates,
+	opts ...optFunc,
+) ([]string, error) {
+	if ts == nil {
+		ts = Templates{}
+	}
+
+	opts = append([]optFunc{Sensitive}, opts...)
+	opts = append(opts, func(o *options) { o.ts = ts })
+
+	return Find(ctx, where, "", opts...)
+}
+
+// FindMany acts the same way as [Find], but searches in each of the
+// roots one by one. Results are concatenated, [Max] limits the total
+// amount of them. If root cannot be resolved, it is skipped only if
+// [WithErrorsSkip] was set.
+func FindMany[T Templater](
+	ctx context.Context,
+	roots []string,
+	t T,
+	opts ...optFunc,
+) ([]string, error) {
+	opt := defaultOptionsWithCustom(opts...)
+
+	if err := opt.compile(); err != nil {
+		return nil, err
+	}
+
+	ts, err := newTemplates(t, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := opt.withTimeout(ctx)
+	defer cancel()
+
+	for _, where := range roots {
+		if opt.limitReached() {
+			break
+		}
+
+		var (
+			resPath string
+			info    fs.FileInfo
+			err     error
+		)
+
+		if opt.expand {
+			where, err = expandPath(where)
+		}
+
+		if err == nil {
+			resPath, info, err = resolvePath(where)
+		}
 
-		ts, err := newTemplates(t, opt.caseFunc)
 		if err != nil {
-			opt.errCh <- err
-			return
+			if lErr := opt.logError(where, err); lErr != nil &&
+				!errors.Is(lErr, ErrSkipDir) {
+				return nil, lErr
+			}
+
+			continue
 		}
 
-		if _, err := find(ctx, resPath, ts, opt); err != nil {
-			opt.errCh <- err
+		opt.orig = where
+		opt.resOrig = resPath
+
+		if err := search(ctx, resPath, info, ts, opt); err != nil {
+			if interrupted(err) {
+				return opt.paths(), err
+			}
+
+			return nil, err
 		}
-	}()
+	}
 
-	return opt.iterCh, opt.errCh
+	return opt.paths(), opt.collected()
 }
 
-// Find searches for matches with the given templates in where.
-func Find[T Templater](
+// FindFS acts the same way as [Find], but searches in the given file
+// system. Where should be a valid [fs.FS] path e.g., "." for the root
+// of fsys, found paths are relative to fsys as well.
+//
+// Note: [fs.FS] does not support symlinks, so [FollowSymlinks]
+// has no effect.
+func FindFS[T Templater](
 	ctx context.Context,
+	fsys fs.FS,
 	where string,
 	t T,
 	opts ...optFunc,
 ) ([]string, error) {
-	// Primary path resolution, even if `skip` flag was set,
-	// this error is critical and should not be omitted.
-	resPath, err := resolvePath(where)
+	if !fs.ValidPath(where) {
+		err := &fs.PathError{Op: "find", Path: where, Err: fs.ErrInvalid}
+
+		return nil, rootError(err)
+	}
+
+	info, err := fs.Stat(fsys, where)
 	if err != nil {
+		return nil, rootError(err)
+	}
+
+	opt := defaultOptionsWithCustom(opts...)
+	opt.fsys = ioFS{fsys}
+	opt.follow = false
+	opt.orig = where
+	opt.resOrig = where
+
+	if err := start(ctx, where, info, t, opt); err != nil {
+		if interrupted(err) {
+			return opt.paths(), err
+		}
+
+		return nil, err
+	}
+
+	return opt.paths(), opt.collected()
+}
+
+// Result is a found path with its file info.
+type Result struct {
+	Path string
+	Info fs.FileInfo
+}
+
+// Match is a found path with the template it matched.
+type Match struct {
+	Path string
+	// The first of the given templates, which matched the path.
+	// With [Strict] it is always the first one, with [WithInvert]
+	// it is nil.
+	Matched *Template
+}
+
+// FindMatches acts the same way as [Find], but returns the template
+// each path matched alongside it, e.g. to group the results.
+//
+// Note: [Sort] has no effect.
+func FindMatches[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	opts ...optFunc,
+) ([]Match, error) {
+	opt := defaultOptionsWithCustom(opts...)
+	opt.matches = true
+
+	if err := run(ctx, where, t, opt); err != nil {
+		if interrupted(err) {
+			return opt.matchRes, err
+		}
+
 		return nil, err
 	}
 
+	return opt.matchRes, opt.collected()
+}
+
+// Walk traverses where the same way as [Find], but calls fn for each
+// entry with the flag if it matched the templates, instead of saving
+// the results. Entries pruned by the options e.g., [Exclude] are not
+// passed to fn, entries over [MaxPerDir] are passed as not matched.
+// If fn returns [fs.SkipDir] for the folder, it is not traversed, for
+// the file - the rest of its folder is skipped. [fs.SkipAll] stops the
+// search. Any other error stops the search and is returned.
+//
+// Note: as with [Find], subfolders are traversed only with [Recursively].
+func Walk[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	fn func(path string, d fs.DirEntry, matched bool) error,
+	opts ...optFunc,
+) error {
 	opt := defaultOptionsWithCustom(opts...)
+	opt.walkFn = fn
+
+	if err := run(ctx, where, t, opt); err != nil {
+		return err
+	}
+
+	return opt.collected()
+}
+
+// FindInfo acts the same way as [Find], but returns file info of
+// each match alongside its path.
+func FindInfo[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	opts ...optFunc,
+) ([]Result, error) {
+	opt := defaultOptionsWithCustom(opts...)
+	opt.info = true
+
+	if err := run(ctx, where, t, opt); err != nil {
+		if interrupted(err) {
+			return opt.results(), err
+		}
+
+		return nil, err
+	}
+
+	return opt.results(), opt.collected()
+}
+
+// Stats are counters of the work done by [FindWithStats].
+type Stats struct {
+	// Number of folders read.
+	DirsScanned int
+	// Number of entries in the read folders, which are not folders.
+	FilesScanned int
+	// Number of found matches.
+	Matches int
+	// Duration of the search.
+	Duration time.Duration
+}
+
+// FindWithStats acts the same way as [Find], but returns counters of
+// the search alongside the results. Stats are returned even if search
+// failed.
+func FindWithStats[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	opts ...optFunc,
+) ([]string, Stats, error) {
+	opt := defaultOptionsWithCustom(opts...)
+	opt.withStats = true
+
+	begin := time.Now()
+	err := run(ctx, where, t, opt)
+
+	stats := Stats{
+		DirsScanned:  opt.stats.DirsScanned,
+		FilesScanned: opt.files,
+		Matches:      opt.stats.Matches,
+		Duration:     time.Since(begin),
+	}
+
+	if err != nil {
+		if interrupted(err) {
+			return opt.paths(), stats, err
+		}
+
+		return nil, stats, err
+	}
+
+	return opt.paths(), stats, opt.collected()
+}
+
+// interrupted reports if the search was stopped by the context
+// or [MaxScanned].
+func interrupted(err error) bool {
+	return errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrScanLimit)
+}
+
+// run prepares options and templates and starts the search in where.
+func run[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	opt *options,
+) error {
+	if opt.expand {
+		var err error
+		if where, err = expandPath(where); err != nil {
+			return err
+		}
+	}
+
+	// Primary path resolution, even if `skip` flag was set,
+	// this error is critical and should not be omitted.
+	resPath, info, err := resolvePath(where)
+	if err != nil {
+		return err
+	}
 
 	// Pre-save location file and its resolved path, for further
 	// usage if relative paths will be needed.
 	opt.orig = where
 	opt.resOrig = resPath
 
-	ts, err := newTemplates(t, opt.caseFunc)
+	return start(ctx, resPath, info, t, opt)
+}
+
+// start compiles templates and starts the search from the root.
+func start[T Templater](
+	ctx context.Context,
+	root string,
+	info fs.FileInfo,
+	t T,
+	opt *options,
+) error {
+	if err := opt.compile(); err != nil {
+		return err
+	}
+
+	ctx, cancel := opt.withTimeout(ctx)
+	defer cancel()
+
+	ts := opt.ts
+
+	// Compiled templates are normalized the same way as matched names.
+	if ts != nil && opt.foldCase {
+		folded := make(Templates, len(ts))
+		for i, t := range ts {
+			folded[i] = t.fold(opt.caseFunc)
+		}
+
+		ts = folded
+	}
+
+	if ts == nil {
+		var err error
+		if ts, err = newTemplates(t, opt); err != nil {
+			return err
+		}
+	}
+
+	return search(ctx, root, info, ts, opt)
+}
+
+// FindFirst acts the same way as [Find] with [Max] set to 1, but returns
+// the first match and true if it was found. Search stops as soon as
+// the match is found.
+func FindFirst[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	opts ...optFunc,
+) (string, bool, error) {
+	// Full slice expression prevents changes of the caller's slice.
+	opts = append(opts[:len(opts):len(opts)], Max(1))
+
+	res, err := Find(ctx, where, t, opts...)
+	if len(res) == 0 {
+		return "", false, err
+	}
+
+	return res[0], true, err
+}
+
+// FindN searches for at most n paths matching the template and stops
+// as soon as they are found. Unlike [Max], n must not be negative,
+// otherwise [ErrNegativeLimit] is returned. Zero n gives no results.
+func FindN[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	n int,
+	opts ...optFunc,
+) ([]string, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrNegativeLimit, n)
+	}
+
+	// Full slice expression prevents changes of the caller's slice.
+	opts = append(opts[:len(opts):len(opts)], Max(n))
+
+	return Find(ctx, where, t, opts...)
+}
+
+// FindDirsContaining searches recursively for folders, which contain
+// at least one entry matching the template e.g., all folders with a
+// Makefile. It acts the same way as [Find] with [Recursively] and
+// [WithParentDirs], so each folder is returned only once.
+func FindDirsContaining[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	opts ...optFunc,
+) ([]string, error) {
+	// Full slice expression prevents changes of the caller's slice.
+	opts = append(opts[:len(opts):len(opts)], Recursively, WithParentDirs)
+
+	return Find(ctx, where, t, opts...)
+}
+
+// dir is a folder visited during the search.
+type dir struct {
+	parent *dir
+	// Absolute path of the folder, found paths are joined with it,
+	// so followed symlinks stay in the output as is.
+	path string
+	// Resolved path of the folder, set only with [FollowSymlinks].
+	resolved string
+	// Depth of the folder below the search root.
+	depth int
+	// Rules of the .gitignore files, set only with [RespectGitignore].
+	ignore ignoreRules
+}
+
+// entry is a file or folder found during the search.
+type entry struct {
+	os.DirEntry
+	path string
+	info fs.FileInfo
+	// Template the entry was counted for with [MaxPerTemplate].
+	tmpl *Template
+	// Entry is over the [MaxPerDir] limit, so it cannot match.
+	overLimit bool
+}
+
+// Info returns file info of the entry. Result is cached, so
+// several filters can use it without extra syscalls.
+func (e *entry) Info() (fs.FileInfo, error) {
+	if e.info == nil {
+		info, err := e.DirEntry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		e.info = info
+	}
+
+	return e.info, nil
+}
+
+// enter checks that folder was not visited by any of its parents,
+// otherwise symlinks formed a loop. Folders visited through other
+// paths are entered again.
+func (d *dir) enter(fsys fileSystem) error {
+	resolved, err := fsys.Resolve(d.path)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return find(ctx, resPath, ts, opt)
+	for p := d.parent; p != nil; p = p.parent {
+		if p.resolved == resolved {
+			return fmt.Errorf("%w: %s", ErrSymlinkLoop, d.path)
+		}
+	}
+
+	d.resolved = resolved
+
+	return nil
 }
 
+// search runs find from the root with the given info and waits
+// for all spawned goroutines if [Concurrency] was set.
+func search(
+	ctx context.Context,
+	root string,
+	info fs.FileInfo,
+	ts Templates,
+	opt *options,
+) (err error) {
+	// Buffered output must be written even if search failed.
+	defer func() {
+		if fErr := opt.flush(); err == nil {
+			err = fErr
+		}
+	}()
+
+	if opt.workers > 1 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+
+		opt.cancel = cancel
+		opt.sem = make(chan struct{}, opt.workers-1)
+	}
+
+	if opt.sameFS {
+		opt.rootDev, opt.hasDev = deviceOf(info)
+	}
+
+	if opt.nested {
+		ts = opt.nestTemplates(ts, root)
+	}
+
+	// File given as the root is matched directly.
+	if !info.IsDir() {
+		return skipDir(matchRoot(root, info, ts, opt))
+	}
+
+	if opt.withRoot && opt.order != PostOrder {
+		if err := matchRoot(root, info, ts, opt); err != nil {
+			return skipDir(err)
+		}
+	}
+
+	if err := find(ctx, &dir{path: root}, ts, opt); err != nil {
+		opt.fail(err)
+	}
+
+	opt.wg.Wait()
+
+	if opt.withRoot && opt.order == PostOrder && opt.failed == nil {
+		if err := matchRoot(root, info, ts, opt); err != nil {
+			opt.fail(skipDir(err))
+		}
+	}
+
+	// Final stats without new folders.
+	opt.report(-1)
+
+	return opt.failed
+}
+
+// find searches in the folder and its subfolders. Folder is skipped
+// if error handler returned [ErrSkipDir].
 func find(
 	ctx context.Context,
-	where string,
+	d *dir,
 	ts Templates,
 	opt *options,
-) ([]string, error) {
-	resPath, data, err := readAndResolve(where)
-	if err != nil {
-		lErr := opt.logError(err)
+) error {
+	return skipDir(walk(ctx, d, ts, opt))
+}
 
-		return nil, lErr
+// skipDir drops [ErrSkipDir] and errors of [Walk] callback, which
+// skip the folder or stop the search, since they are not actual errors.
+func skipDir(err error) error {
+	if errors.Is(err, ErrSkipDir) ||
+		errors.Is(err, fs.SkipDir) ||
+		errors.Is(err, fs.SkipAll) {
+		return nil
 	}
 
-	res := make([]string, 0)
+	return err
+}
+
+func walk(
+	ctx context.Context,
+	d *dir,
+	ts Templates,
+	opt *options,
+) error {
+	if opt.follow {
+		if err := d.enter(opt.fsys); err != nil {
+			return opt.logError(d.path, err)
+		}
+	}
+
+	if opt.gitignore {
+		if err := d.loadIgnore(opt.fsys); err != nil {
+			if lErr := opt.logError(d.path, err); lErr != nil {
+				return lErr
+			}
+		}
+	}
+
+	opt.trace("enter", d.path, "")
+
+	data, err := opt.fsys.ReadDir(d.path)
+	if err != nil {
+		return opt.logError(d.path, err)
+	}
+
+	opt.report(len(data))
+	opt.countFiles(data)
+
+	// Number of matches in the folder for [MaxPerDir].
+	found := 0
 
 	for _, f := range data {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return ctx.Err()
 		default:
-			if opt.max == 0 {
-				return res, nil
+			if opt.limitReached() {
+				return nil
 			}
 
-			p := filepath.Join(resPath, f.Name())
+			if err := opt.scan(); err != nil {
+				return err
+			}
+
+			p := opt.fsys.Join(d.path, f.Name())
+
+			if reason := opt.prune(d, p, f); reason != "" {
+				opt.trace("prune", p, reason)
 
-			var found string
+				continue
+			}
+
+			e := &entry{DirEntry: f, path: p}
 
-			if opt.isSearchedType(f.IsDir()) && opt.match(ts, p) {
-				switch {
-				case opt.name:
-					found = f.Name()
-				case opt.relative:
-					found = strings.ReplaceAll(p, opt.resOrig, opt.orig)
-				default:
-					found = p
+			isDir, err := opt.isDir(e)
+			if err != nil {
+				if lErr := opt.logError(e.path, err); lErr != nil {
+					return lErr
 				}
 
-				if err := opt.printOutput(found); err != nil {
-					return nil, err
+				continue
+			}
+
+			// Entries over the limit are only traversed and passed
+			// to [Walk] as not matched.
+			match := func() error {
+				if opt.maxPerDir > 0 && found >= opt.maxPerDir {
+					if opt.walkFn == nil {
+						return nil
+					}
+
+					e.overLimit = true
 				}
 
-				if opt.iter {
-					opt.iterCh <- found
-				} else {
-					res = append(res, found)
+				ok, err := process(d, e, isDir, ts, opt)
+				if ok {
+					found++
 				}
 
-				if opt.max != -1 {
-					opt.max--
+				return err
+			}
+
+			rec := opt.rec && isDir && opt.canDescend(d.depth)
+			if opt.rec && isDir && !rec {
+				opt.trace("prune", p, "max depth")
+			}
+
+			if rec && !opt.canNest(ts, p, d.depth+1) {
+				opt.trace("prune", p, "nested templates")
+
+				rec = false
+			}
+
+			if rec && !opt.onRootDevice(e) {
+				opt.trace("prune", p, "other filesystem")
+
+				rec = false
+			}
+			post := rec && opt.order == PostOrder
+
+			if !post {
+				if err := match(); err != nil {
+					// Only the folder itself is skipped.
+					if !isDir || !errors.Is(err, fs.SkipDir) {
+						return err
+					}
+
+					rec = false
 				}
 			}
 
-			if opt.rec && f.IsDir() {
-				recData, err := find(ctx, p, ts, opt)
+			if rec {
+				sub := &dir{parent: d, path: p, depth: d.depth + 1}
+
+				// Folder must be matched after the whole subtree,
+				// so it cannot be passed to another worker.
+				if post {
+					err = find(ctx, sub, ts, opt)
+				} else {
+					err = descend(ctx, sub, ts, opt)
+				}
+
 				if err != nil {
-					return nil, err
+					return err
 				}
+			}
 
-				res = append(res, recData...)
+			// Folder is already traversed, so SkipDir has no effect.
+			if post {
+				if err := match(); err != nil &&
+					(!isDir || !errors.Is(err, fs.SkipDir)) {
+					return err
+				}
 			}
 		}
 	}
 
-	return res, nil
+	return nil
+}
+
+// matchRoot checks if the root itself matches templates.
+func matchRoot(
+	root string,
+	info fs.FileInfo,
+	ts Templates,
+	opt *options,
+) error {
+	// Info of the unresolved root can be named e.g., '.', while
+	// the resolved name is matched.
+	info = rootInfo{FileInfo: info, name: filepath.Base(root)}
+
+	e := &entry{
+		DirEntry: fs.FileInfoToDirEntry(info),
+		path:     root,
+		info:     info,
+	}
+
+	_, err := process(&dir{}, e, info.IsDir(), ts, opt)
+
+	return err
+}
+
+// rootInfo is the file info of the root with its resolved name.
+type rootInfo struct {
+	fs.FileInfo
+	name string
 }
 
-// resolvePath resolves symlinks and relative paths.
-func resolvePath(p string) (string, error) {
+func (r rootInfo) Name() string { return r.name }
+
+// process matches the entry and emits it if it was found. Reports if
+// entry was found, returns only critical errors.
+func process(
+	d *dir,
+	e *entry,
+	isDir bool,
+	ts Templates,
+	opt *options,
+) (bool, error) {
+	if opt.walkFn != nil {
+		return opt.visitEntry(ts, d, e, isDir)
+	}
+
+	matched, err := opt.matchEntry(ts, d, e, isDir)
+	if err != nil {
+		if lErr := opt.logError(e.path, err); lErr != nil {
+			return false, lErr
+		}
+	}
+
+	if matched && opt.needInfo() {
+		// Info is required only for the found entries.
+		if _, err := e.Info(); err != nil {
+			if lErr := opt.logError(e.path, err); lErr != nil {
+				return false, lErr
+			}
+
+			matched = false
+		}
+	}
+
+	if matched && opt.unique {
+		dup, err := opt.isDuplicate(e)
+		if err != nil {
+			if lErr := opt.logError(e.path, err); lErr != nil {
+				return false, lErr
+			}
+		}
+
+		matched = err == nil && !dup
+	}
+
+	if matched && opt.linkDedup {
+		dup, err := opt.isHardlink(e)
+		if err != nil {
+			if lErr := opt.logError(e.path, err); lErr != nil {
+				return false, lErr
+			}
+		}
+
+		matched = err == nil && !dup
+	}
+
+	// Inverted match has no template to count.
+	if matched && opt.tmplLimit > 0 && !opt.invert {
+		e.tmpl = opt.takeTemplate(ts, e.path)
+		matched = e.tmpl != nil
+	}
+
+	if matched {
+		if err := opt.emitEntry(ts, e); err != nil {
+			return false, err
+		}
+	}
+
+	if opt.archives && !isDir && isArchive(e.Name()) {
+		if err := opt.searchArchive(ts, d, e); err != nil {
+			if lErr := opt.logError(e.path, err); lErr != nil {
+				return false, lErr
+			}
+		}
+	}
+
+	return matched, nil
+}
+
+// descend runs find in the subfolder. If [Concurrency] was set and
+// there is a free worker, subfolder is processed in the new goroutine,
+// otherwise in the current one.
+func descend(ctx context.Context, d *dir, ts Templates, opt *options) error {
+	if opt.sem != nil {
+		select {
+		case opt.sem <- struct{}{}:
+			opt.wg.Add(1)
+
+			go func() {
+				defer func() {
+					<-opt.sem
+					opt.wg.Done()
+				}()
+
+				if err := find(ctx, d, ts, opt); err != nil {
+					opt.fail(err)
+				}
+			}()
+
+			return nil
+		default:
+		}
+	}
+
+	return find(ctx, d, ts, opt)
+}
+
+// fileSystem abstracts access to the searched files.
+type fileSystem interface {
+	Open(name string) (fs.File, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	Join(elem ...string) string
+	Dir(name string) string
+	// Resolve returns the path with all the symlinks evaluated.
+	Resolve(name string) (string, error)
+}
+
+// dirReader reads entries of the folder.
+type dirReader func(name string) ([]fs.DirEntry, error)
+
+// readerFS reads folders with the custom [dirReader], the rest is
+// done by the underlying file system.
+type readerFS struct {
+	fileSystem
+	read dirReader
+}
+
+func (r readerFS) ReadDir(name string) ([]fs.DirEntry, error) { return r.read(name) }
+
+// osFS provides access to the files of the operating system.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error)          { return os.Open(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) Join(elem ...string) string                 { return filepath.Join(elem...) }
+func (osFS) Dir(name string) string                     { return filepath.Dir(name) }
+func (osFS) Resolve(name string) (string, error)        { return filepath.EvalSymlinks(name) }
+
+// ioFS provides access to the files of [fs.FS].
+type ioFS struct {
+	fsys fs.FS
+}
+
+func (f ioFS) Open(name string) (fs.File, error)          { return f.fsys.Open(name) }
+func (f ioFS) ReadDir(name string) ([]fs.DirEntry, error) { return fs.ReadDir(f.fsys, name) }
+func (f ioFS) Stat(name string) (fs.FileInfo, error)      { return fs.Stat(f.fsys, name) }
+func (ioFS) Join(elem ...string) string                   { return path.Join(elem...) }
+func (ioFS) Dir(name string) string                       { return path.Dir(name) }
+func (ioFS) Resolve(name string) (string, error)          { return name, nil }
+
+// expandPath replaces leading '~' with the home folder of the user and
+// environment variables with their values for [ExpandPath].
+func expandPath(p string) (string, error) {
+	if p == "~" || strings.HasPrefix(p, "~/") ||
+		strings.HasPrefix(p, "~"+pathSeparator) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+
+		p = home + p[1:]
+	}
+
+	return os.ExpandEnv(p), nil
+}
+
+// resolvePath resolves symlinks and relative paths. Returns info
+// of the resolved path, so the root is not checked again.
+func resolvePath(p string) (string, fs.FileInfo, error) {
 	info, err := os.Lstat(p)
 	if err != nil {
-		return "", err
+		return "", nil, rootError(err)
 	}
 
 	if info.Mode()&os.ModeSymlink == os.ModeSymlink {
 		if p, err = filepath.EvalSymlinks(p); err != nil {
-			return "", err
+			return "", nil, rootError(err)
 		}
+
+		if info, err = os.Stat(p); err != nil {
+			return "", nil, rootError(err)
+		}
+	}
+
+	p, err = filepath.Abs(p)
+	if err != nil {
+		return "", nil, rootError(err)
 	}
 
-	return filepath.Abs(p)
+	return p, info, nil
 }
 
-func readAndResolve(p string) (string, []os.DirEntry, error) {
-	resPath, err := resolvePath(p)
+// rootError wraps the error of the root resolution with
+// [ErrRootNotFound] or [ErrInvalidRoot].
+func rootError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %w", ErrRootNotFound, err)
+	}
+
+	return fmt.Errorf("%w: %w", ErrInvalidRoot, err)
+}
+
+func newTemplates[T Templater](t T, opt *options) (Templates, error) {
+	sl, err := templateStrings(t)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
 
-	data, err := os.ReadDir(resPath)
+	if opt.glob {
+		return globTemplates(sl, opt.caseFunc)
+	}
+
+	if opt.exact {
+		return literalTemplates(sl, opt.caseFunc), nil
+	}
 
-	return resPath, data, err
+	return compileTemplates(sl, opt.caseFunc)
 }
 
-func newTemplates[T Templater](t T, fn caseFunc) (Templates, error) {
-	var ts Templates
+// literalTemplates creates templates, which match only names equal
+// to the strings for [ExactName].
+func literalTemplates(sl []string, fn caseFunc) Templates {
+	ts := make(Templates, 0, len(sl))
+
+	for _, str := range sl {
+		ts = append(ts, &Template{
+			base:        fn(str),
+			literal:     true,
+			strictLeft:  true,
+			strictRight: true,
+		})
+	}
+
+	return ts
+}
 
+// templateStrings converts generic templates into the slice of strings.
+func templateStrings[T Templater](t T) ([]string, error) {
 	switch any(t).(type) {
 	case string:
-		ts = Templates{NewTemplate(fn(any(t).(string)))}
+		return []string{any(t).(string)}, nil
 	case []string:
-		sl := make([]string, 0, len(any(t).([]string)))
+		return any(t).([]string), nil
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrTemplateType, t)
+	}
+}
 
-		for _, str := range any(t).([]string) {
-			sl = append(sl, fn(str))
+// nestTemplates returns copies of the templates, which match their
+// '&' chains against the folders below the root for [NestedAnd].
+func (o *options) nestTemplates(ts Templates, root string) Templates {
+	// Templates without chain match the same string as without
+	// [NestedAnd], full path includes the root folders.
+	n := &nesting{whole: o.full}
+	if o.full && !o.relMatch {
+		if p := strings.TrimSuffix(o.caseFunc.apply(toSlash(root)), separator); p != "." {
+			n.skip = strings.Count(p, separator) + 1
 		}
+	}
 
-		ts = NewTemplates(sl)
-	default:
-		return nil, fmt.Errorf("%w: %v", ErrTemplateType, t)
+	nested := make(Templates, len(ts))
+
+	for i, t := range ts {
+		c := *t
+		c.nest = n
+		nested[i] = &c
+	}
+
+	return nested
+}
+
+func globTemplates(sl []string, fn caseFunc) (Templates, error) {
+	ts := make(Templates, 0, len(sl))
+
+	for _, str := range sl {
+		t, err := NewGlobTemplate(fn.apply(str))
+		if err != nil {
+			return nil, err
+		}
+
+		ts = append(ts, t)
+	}
+
+	return ts, nil
+}
+
+func compileTemplates(sl []string, fn caseFunc) (Templates, error) {
+	ts := make(Templates, 0, len(sl))
+
+	for _, str := range sl {
+		t, err := CompileTemplate(fn.apply(str))
+		if err != nil {
+			return nil, err
+		}
+
+		ts = append(ts, t)
 	}
 
 	return ts, nil
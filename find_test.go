@@ -1,13 +1,121 @@
 package find
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"maps"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
 	"time"
 )
 
+// makeTree creates files and folders (ending with '/') in the
+// temporary directory and returns its path.
+func makeTree(t testing.TB, paths ...string) string {
+	t.Helper()
+
+	root := t.TempDir()
+
+	for _, p := range paths {
+		full := filepath.Join(root, filepath.FromSlash(p))
+
+		if strings.HasSuffix(p, "/") {
+			if err := os.MkdirAll(full, 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(full, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+// relPaths converts search results into sorted slash separated paths
+// relative to root.
+func relPaths(t *testing.T, root string, res []string) []string {
+	t.Helper()
+
+	rel := make([]string, 0, len(res))
+
+	for _, p := range res {
+		r, err := filepath.Rel(root, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rel = append(rel, filepath.ToSlash(r))
+	}
+
+	slices.Sort(rel)
+
+	return rel
+}
+
+// mustFind runs [Find] and returns results relative to where.
+func mustFind(
+	t *testing.T,
+	where string,
+	template any,
+	opts ...optFunc,
+) []string {
+	t.Helper()
+
+	var (
+		res []string
+		err error
+	)
+
+	switch tmpl := template.(type) {
+	case string:
+		res, err = Find(context.Background(), where, tmpl, opts...)
+	case []string:
+		res, err = Find(context.Background(), where, tmpl, opts...)
+	default:
+		t.Fatalf("unsupported template type %T", template)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return relPaths(t, where, res)
+}
+
+func assertPaths(t *testing.T, got []string, want ...string) {
+	t.Helper()
+
+	if want == nil {
+		want = []string{}
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 func ExampleFind() {
 	ctx, cancel := context.WithDeadline(
 		context.Background(),
@@ -76,6 +184,23 @@ func ExampleTemplate_standalone() {
 	}
 }
 
+func ExampleTemplate_Insensitive() {
+	// Matches 'README.md', 'readme.txt', 'ReadMe', etc.
+	template := NewTemplate("readme*").Insensitive()
+
+	// Can be any string slice, resulted from different sources.
+	s, err := os.ReadDir("some/folder")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	for _, el := range s {
+		if template.Match(el.Name()) {
+			// Do something here...
+		}
+	}
+}
+
 func ExampleTemplates() {
 	ts := []string{"*this*", "*that*"}
 
@@ -97,3 +222,2694 @@ func ExampleTemplates() {
 		}
 	}
 }
+
+func ExampleTemplates_MatchAny() {
+	templates := NewTemplates([]string{"*this*", "*that*"})
+
+	fmt.Println(templates.MatchAny("this file"))
+	fmt.Println(templates.MatchAll("this file"))
+	fmt.Println(templates.MatchAll("this and that"))
+	// Output:
+	// true
+	// false
+	// true
+}
+
+func TestMaxDepth(t *testing.T) {
+	root := makeTree(t, "f0", "a/f1", "a/b/f2", "a/b/c/f3")
+
+	tests := []struct {
+		depth int
+		want  []string
+	}{
+		{0, []string{"a", "f0"}},
+		{1, []string{"a", "a/b", "a/f1", "f0"}},
+		{2, []string{"a", "a/b", "a/b/c", "a/b/f2", "a/f1", "f0"}},
+		{-1, []string{
+			"a", "a/b", "a/b/c", "a/b/c/f3", "a/b/f2", "a/f1", "f0",
+		}},
+	}
+
+	for _, tt := range tests {
+		got := mustFind(t, root, "*", Recursively, MaxDepth(tt.depth))
+		assertPaths(t, got, tt.want...)
+	}
+}
+
+func TestMinDepth(t *testing.T) {
+	root := makeTree(t, "f0", "a/f1", "a/b/f2", "a/b/c/f3")
+
+	got := mustFind(t, root, "*", Recursively, MinDepth(2))
+	assertPaths(t, got, "a/b/c", "a/b/c/f3", "a/b/f2")
+
+	got = mustFind(t, root, "*", Recursively, MinDepth(2), Max(2))
+	assertPaths(t, got, "a/b/c", "a/b/c/f3")
+
+	outCh, errCh := FindWithIterator(
+		context.Background(), root, "f*", Recursively, MinDepth(2),
+	)
+
+	var res []string
+	for f := range outCh {
+		res = append(res, f)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "a/b/c/f3", "a/b/f2")
+}
+
+// symlink creates symlink or skips the test if it is not supported.
+func symlink(t *testing.T, oldname, newname string) {
+	t.Helper()
+
+	if err := os.Symlink(oldname, newname); err != nil {
+		t.Skipf("symlinks are not supported: %v", err)
+	}
+}
+
+func TestFollowSymlinks(t *testing.T) {
+	root := makeTree(t, "real/f1")
+	outside := makeTree(t, "f2", "sub/f3")
+
+	symlink(t, outside, filepath.Join(root, "link"))
+	symlink(t, filepath.Join(root, "missing"), filepath.Join(root, "broken"))
+
+	got := mustFind(t, root, "*", Recursively)
+	assertPaths(t, got, "broken", "link", "real", "real/f1")
+
+	got = mustFind(t, root, "*", Recursively, FollowSymlinks)
+	assertPaths(t, got,
+		"broken", "link", "link/f2", "link/sub", "link/sub/f3",
+		"real", "real/f1",
+	)
+
+	got = mustFind(t, root, "*", Recursively, FollowSymlinks, Only(Folder))
+	assertPaths(t, got, "link", "link/sub", "real")
+}
+
+func TestFollowSymlinks_loop(t *testing.T) {
+	root := makeTree(t, "a/f1")
+
+	symlink(t, root, filepath.Join(root, "a", "loop"))
+
+	_, err := Find(context.Background(), root, "*", Recursively, FollowSymlinks)
+	if !errors.Is(err, ErrSymlinkLoop) {
+		t.Fatalf("got %v, want %v", err, ErrSymlinkLoop)
+	}
+
+	got := mustFind(t, root, "*", Recursively, FollowSymlinks, WithErrorsSkip)
+	assertPaths(t, got, "a", "a/f1", "a/loop")
+}
+
+func TestFollowSymlinks_shared(t *testing.T) {
+	root := makeTree(t, "real/f1")
+
+	symlink(t, filepath.Join(root, "real"), filepath.Join(root, "l1"))
+	symlink(t, filepath.Join(root, "real"), filepath.Join(root, "l2"))
+
+	// Folder is not a loop, so it is traversed through each symlink.
+	got := mustFind(t, root, "f1", Recursively, FollowSymlinks)
+	assertPaths(t, got, "l1/f1", "l2/f1", "real/f1")
+
+	got = mustFind(t, root, "f1", Recursively, FollowSymlinks, Unique)
+	if len(got) != 1 {
+		t.Errorf("got %q, want single path", got)
+	}
+}
+
+func TestSkipHidden(t *testing.T) {
+	root := makeTree(t, ".env", ".git/config", "src/.cache", "src/main.go")
+
+	got := mustFind(t, root, "*", Recursively, SkipHidden)
+	assertPaths(t, got, "src", "src/main.go")
+
+	hidden := filepath.Join(root, ".git")
+
+	got = mustFind(t, hidden, "*", Recursively, SkipHidden)
+	assertPaths(t, got, "config")
+}
+
+func TestExclude(t *testing.T) {
+	root := makeTree(t,
+		"main.go",
+		"node_modules/pkg/index.js",
+		"vendor/lib/lib.go",
+		"src/vendor.go",
+		"src/vendor/lib.go",
+	)
+
+	got := mustFind(t, root, "*", Recursively, Exclude("node_modules"))
+	assertPaths(t, got,
+		"main.go", "src", "src/vendor", "src/vendor.go", "src/vendor/lib.go",
+		"vendor", "vendor/lib", "vendor/lib/lib.go",
+	)
+
+	got = mustFind(t, root, "*.go", Recursively,
+		Exclude([]string{"node_modules", "vendor"}),
+	)
+	assertPaths(t, got, "main.go", "src/vendor.go")
+
+	got = mustFind(t, root, "*.go", Recursively,
+		MatchFullPath, Exclude("*/src/vendor"),
+	)
+	assertPaths(t, got, "main.go", "src/vendor.go", "vendor/lib/lib.go")
+
+	got = mustFind(t, root, "*.go", Recursively, Exclude("VENDOR"), Insensitive)
+	assertPaths(t, got, "main.go", "src/vendor.go")
+
+	_, err := Find(context.Background(), root, "*", Exclude("(vendor"))
+	if !errors.Is(err, ErrTemplateSyntax) {
+		t.Errorf("got %v, want %v", err, ErrTemplateSyntax)
+	}
+}
+
+func TestMinSizeMaxSize(t *testing.T) {
+	root := makeTree(t, "dir/")
+
+	for name, size := range map[string]int{
+		"empty": 0, "small": 10, "medium": 100, "large": 1000,
+	} {
+		data := make([]byte, size)
+
+		err := os.WriteFile(filepath.Join(root, name), data, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := mustFind(t, root, "*", MinSize(100))
+	assertPaths(t, got, "dir", "large", "medium")
+
+	got = mustFind(t, root, "*", MaxSize(10), Only(File))
+	assertPaths(t, got, "empty", "small")
+
+	got = mustFind(t, root, "*", MinSize(10), MaxSize(100), Only(File))
+	assertPaths(t, got, "medium", "small")
+}
+
+func TestModifiedAfterBefore(t *testing.T) {
+	root := makeTree(t, "old", "mid", "new")
+	now := time.Now()
+
+	for name, mtime := range map[string]time.Time{
+		"old": now.Add(-72 * time.Hour),
+		"mid": now.Add(-24 * time.Hour),
+		"new": now,
+	} {
+		err := os.Chtimes(filepath.Join(root, name), mtime, mtime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := mustFind(t, root, "*", ModifiedAfter(now.Add(-48*time.Hour)))
+	assertPaths(t, got, "mid", "new")
+
+	got = mustFind(t, root, "*", ModifiedBefore(now.Add(-time.Hour)))
+	assertPaths(t, got, "mid", "old")
+
+	got = mustFind(t, root, "*",
+		ModifiedAfter(now.Add(-48*time.Hour)),
+		ModifiedBefore(now.Add(-time.Hour)),
+	)
+	assertPaths(t, got, "mid")
+}
+
+// makeWideTree creates tree with n folders on each of the depth
+// levels and n files in each folder.
+func makeWideTree(t *testing.T, n, depth int) string {
+	t.Helper()
+
+	var paths []string
+
+	var fill func(prefix string, level int)
+	fill = func(prefix string, level int) {
+		for i := 0; i < n; i++ {
+			paths = append(paths, fmt.Sprintf("%sf%d", prefix, i))
+
+			if level < depth {
+				fill(fmt.Sprintf("%sd%d/", prefix, i), level+1)
+			}
+		}
+	}
+
+	fill("", 1)
+
+	return makeTree(t, paths...)
+}
+
+func TestConcurrency(t *testing.T) {
+	root := makeWideTree(t, 5, 3)
+
+	want := mustFind(t, root, "*", Recursively)
+
+	for _, n := range []int{2, 4, 16} {
+		got := mustFind(t, root, "*", Recursively, Concurrency(n))
+		assertPaths(t, got, want...)
+
+		got = mustFind(t, root, "f*", Recursively, Concurrency(n), Max(7))
+		if len(got) != 7 {
+			t.Errorf("Concurrency(%d): got %d results, want 7", n, len(got))
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Find(ctx, root, "*", Recursively, Concurrency(4))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWithWriter(t *testing.T) {
+	root := makeTree(t, "a.txt", "b.txt", "c.md")
+
+	stdout := os.Stdout
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Stdout = w
+
+	var buf bytes.Buffer
+
+	res, err := Find(context.Background(), root, "*.txt", WithWriter(&buf))
+
+	os.Stdout = stdout
+
+	w.Close()
+
+	leaked, rErr := io.ReadAll(r)
+	if rErr != nil {
+		t.Fatal(rErr)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "a.txt", "b.txt")
+
+	if want := strings.Join(res, "\n") + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	if len(leaked) != 0 {
+		t.Errorf("unexpected output to stdout: %q", leaked)
+	}
+}
+
+func TestFindFirst(t *testing.T) {
+	root := makeWideTree(t, 3, 3)
+
+	for _, opts := range []Options{
+		{Recursively},
+		{Recursively, Concurrency(4)},
+	} {
+		p, ok, err := FindFirst(context.Background(), root, "f2", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ok || filepath.Base(p) != "f2" {
+			t.Errorf("got %q, %v, want f2, true", p, ok)
+		}
+
+		p, ok, err = FindFirst(context.Background(), root, "missing", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ok || p != "" {
+			t.Errorf("got %q, %v, want empty string, false", p, ok)
+		}
+	}
+}
+
+func TestFindInfo(t *testing.T) {
+	root := makeTree(t, "dir/", "dir/b.txt")
+
+	err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := FindInfo(context.Background(), root, "*", Recursively)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 3 {
+		t.Fatalf("got %d results, want 3", len(res))
+	}
+
+	for _, r := range res {
+		want, err := os.Stat(r.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if r.Info.Name() != want.Name() ||
+			r.Info.Size() != want.Size() ||
+			r.Info.Mode() != want.Mode() ||
+			!r.Info.ModTime().Equal(want.ModTime()) {
+			t.Errorf("%s: info does not match the file on disk", r.Path)
+		}
+	}
+}
+
+func TestFindFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":         {},
+		"README.md":       {},
+		"pkg/find.go":     {},
+		"pkg/sub/util.go": {},
+		"pkg/sub/doc.md":  {},
+	}
+
+	res, err := FindFS(context.Background(), fsys, ".", "*.go", Recursively)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slices.Sort(res)
+	assertPaths(t, res, "main.go", "pkg/find.go", "pkg/sub/util.go")
+
+	res, err = FindFS(context.Background(), fsys, "pkg", "*", Only(Folder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, "pkg/sub")
+
+	if _, err := FindFS(context.Background(), fsys, "missing", "*"); err == nil {
+		t.Error("expected error for missing root")
+	}
+
+	if _, err := FindFS(context.Background(), fsys, "/pkg", "*"); err == nil {
+		t.Error("expected error for invalid root")
+	}
+}
+
+func TestFindMany(t *testing.T) {
+	first := makeTree(t, "a.txt", "sub/b.txt")
+	second := makeTree(t, "c.txt", "d.md")
+	missing := filepath.Join(first, "missing")
+
+	res, err := FindMany(
+		context.Background(), []string{first, second}, "*.txt", Recursively,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		filepath.Join(first, "a.txt"),
+		filepath.Join(first, "sub", "b.txt"),
+		filepath.Join(second, "c.txt"),
+	}
+	assertPaths(t, res, want...)
+
+	res, err = FindMany(
+		context.Background(), []string{first, second}, "*.txt",
+		Recursively, Max(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, want[:2]...)
+
+	roots := []string{missing, first, second}
+
+	if _, err := FindMany(context.Background(), roots, "*.txt"); err == nil {
+		t.Error("expected error for missing root")
+	}
+
+	res, err = FindMany(context.Background(), roots, "*.txt", WithErrorsSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, want[0], want[2])
+}
+
+func TestSort(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+
+	for i, name := range []string{"b", "c", "a"} {
+		p := filepath.Join(root, name)
+
+		if err := os.WriteFile(p, make([]byte, i*10), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		mtime := now.Add(time.Duration(-i) * time.Hour)
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		mode SortMode
+		want []string
+	}{
+		{SortByName, []string{"a", "b", "c"}},
+		{SortByName | SortDesc, []string{"c", "b", "a"}},
+		{SortBySize, []string{"b", "c", "a"}},
+		{SortBySize | SortDesc, []string{"a", "c", "b"}},
+		{SortByMTime, []string{"a", "c", "b"}},
+		{SortByMTime | SortDesc, []string{"b", "c", "a"}},
+		{SortDesc, []string{"c", "b", "a"}},
+	}
+
+	for _, tt := range tests {
+		res, err := Find(
+			context.Background(), root, "*", Name, Concurrency(4), Sort(tt.mode),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertPaths(t, res, tt.want...)
+
+		info, err := FindInfo(context.Background(), root, "*", Sort(tt.mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := make([]string, 0, len(info))
+		for _, r := range info {
+			got = append(got, filepath.Base(r.Path))
+		}
+
+		assertPaths(t, got, tt.want...)
+	}
+}
+
+func TestRespectGitignore(t *testing.T) {
+	root := makeTree(t,
+		"main.go",
+		"debug.log",
+		"build/out.bin",
+		"docs/build/index.html",
+		"src/app.log",
+		"src/keep.log",
+		"src/tmp/cache",
+		"src/lib/tmp.go",
+		"src/lib/gen/types.go",
+	)
+
+	ignores := map[string]string{
+		".gitignore":     "# comment\n\n*.log\n/build/\ntmp/\n",
+		"src/.gitignore": "!keep.log\nlib/**/types.go\n",
+	}
+
+	for name, data := range ignores {
+		err := os.WriteFile(filepath.Join(root, name), []byte(data), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := mustFind(t, root, "*", Recursively, Only(File), RespectGitignore)
+	assertPaths(t, got,
+		".gitignore",
+		"docs/build/index.html",
+		"main.go",
+		"src/.gitignore",
+		"src/keep.log",
+		"src/lib/tmp.go",
+	)
+
+	fsys := os.DirFS(root)
+
+	res, err := FindFS(context.Background(), fsys, ".", "*.log",
+		Recursively, RespectGitignore,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, "src/keep.log")
+}
+
+func TestWithVisitor(t *testing.T) {
+	root := makeWideTree(t, 3, 2)
+
+	var visited []string
+
+	res, err := Find(context.Background(), root, "*", Recursively,
+		WithVisitor(func(p string) error {
+			visited = append(visited, p)
+
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 0 || len(visited) != 15 {
+		t.Errorf("got %d results and %d visits, want 0 and 15",
+			len(res), len(visited))
+	}
+
+	for _, n := range []int{1, 4} {
+		count := 0
+
+		_, err = Find(context.Background(), root, "*", Recursively,
+			Concurrency(n),
+			WithVisitor(func(string) error {
+				if count++; count == 2 {
+					return ErrStopWalk
+				}
+
+				return nil
+			}),
+		)
+		if err != nil {
+			t.Errorf("Concurrency(%d): %v", n, err)
+		}
+
+		if count != 2 {
+			t.Errorf("Concurrency(%d): visited %d times, want 2", n, count)
+		}
+	}
+
+	errVisit := errors.New("visit")
+
+	_, err = Find(context.Background(), root, "*", Recursively,
+		WithVisitor(func(string) error { return errVisit }),
+	)
+	if !errors.Is(err, errVisit) {
+		t.Errorf("got %v, want %v", err, errVisit)
+	}
+}
+
+func TestUnique(t *testing.T) {
+	root := makeTree(t, "real/file.txt", "other.txt")
+
+	symlink(t, filepath.Join(root, "real"), filepath.Join(root, "link"))
+	symlink(t,
+		filepath.Join(root, "real", "file.txt"),
+		filepath.Join(root, "file-link.txt"),
+	)
+
+	got := mustFind(t, root, "*.txt", Recursively, FollowSymlinks)
+	assertPaths(t, got,
+		"file-link.txt", "link/file.txt", "other.txt", "real/file.txt",
+	)
+
+	got = mustFind(t, root, "*.txt", Recursively, FollowSymlinks, Unique)
+	if len(got) != 2 || !slices.Contains(got, "other.txt") {
+		t.Errorf("got %q, want other.txt and one of the file.txt paths", got)
+	}
+
+	roots := []string{root, filepath.Join(root, "real")}
+
+	res, err := FindMany(context.Background(), roots, "*.txt",
+		Recursively, Unique, Concurrency(4),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 {
+		t.Errorf("got %q, want 2 unique paths", res)
+	}
+}
+
+func TestWithMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits are not supported")
+	}
+
+	root := makeTree(t, "private", "shared", "script", "setuid")
+
+	for name, mode := range map[string]fs.FileMode{
+		"private": 0o600,
+		"shared":  0o666,
+		"script":  0o755,
+		"setuid":  0o755 | fs.ModeSetuid,
+	} {
+		if err := os.Chmod(filepath.Join(root, name), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := mustFind(t, root, "*", WithMode(0o002, 0o002))
+	assertPaths(t, got, "shared")
+
+	got = mustFind(t, root, "*", WithMode(0o001, 0o001))
+	assertPaths(t, got, "script", "setuid")
+
+	got = mustFind(t, root, "*", WithMode(fs.ModeSetuid, fs.ModeSetuid))
+	assertPaths(t, got, "setuid")
+
+	got = mustFind(t, root, "*", WithMode(0o077, 0))
+	assertPaths(t, got, "private")
+}
+
+func TestRelativePaths(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "data")
+
+	// Nested folders repeat the resolved root path inside it.
+	nested := filepath.Join(root, root, "data")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(nested, "file.txt")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(parent); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	for _, where := range []string{"data", "data" + string(os.PathSeparator)} {
+		res, err := Find(context.Background(), where, "file.txt",
+			Recursively, RelativePaths,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := filepath.Join("data", root, "data", "file.txt")
+		assertPaths(t, res, want)
+	}
+}
+
+// unreadableFS fails to read or open the given files and folders.
+type unreadableFS struct {
+	osFS
+	dirs []string
+}
+
+func (u unreadableFS) Open(name string) (fs.File, error) {
+	if slices.Contains(u.dirs, filepath.Base(name)) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+
+	return u.osFS.Open(name)
+}
+
+func (u unreadableFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if slices.Contains(u.dirs, filepath.Base(name)) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrPermission}
+	}
+
+	return u.osFS.ReadDir(name)
+}
+
+func withUnreadable(dirs ...string) optFunc {
+	return func(o *options) { o.fsys = unreadableFS{dirs: dirs} }
+}
+
+func collect(outCh chan string, errCh chan error) ([]string, error) {
+	var res []string
+	for f := range outCh {
+		res = append(res, f)
+	}
+
+	return res, <-errCh
+}
+
+func TestFindWithIterator_errors(t *testing.T) {
+	root := makeTree(t, "a/", "b/", "c/1.txt", "2.txt")
+
+	t.Run("skip", func(t *testing.T) {
+		res, err := collect(FindWithIterator(context.Background(), root, "*.txt",
+			Recursively, WithErrorsSkip, withUnreadable("a", "b"),
+		))
+
+		assertPaths(t, relPaths(t, root, res), "2.txt", "c/1.txt")
+
+		if !errors.Is(err, fs.ErrPermission) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		for _, dir := range []string{"a", "b"} {
+			if !strings.Contains(err.Error(), filepath.Join(root, dir)) {
+				t.Errorf("error for %q is missing in %v", dir, err)
+			}
+		}
+	})
+
+	t.Run("critical", func(t *testing.T) {
+		_, err := collect(FindWithIterator(context.Background(), root, "*.txt",
+			Recursively, withUnreadable("a", "b"),
+		))
+		if !errors.Is(err, fs.ErrPermission) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if n := strings.Count(err.Error(), "\n"); n != 0 {
+			t.Errorf("expected single error, got %v", err)
+		}
+	})
+}
+
+// writerFunc is an adapter to use function as [io.Writer].
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestFind_canceled(t *testing.T) {
+	root := makeWideTree(t, 5, 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var n int
+	w := writerFunc(func(p []byte) (int, error) {
+		if n++; n == 3 {
+			cancel()
+		}
+
+		return len(p), nil
+	})
+
+	res, err := Find(ctx, root, "*", Recursively, WithOutput, WithWriter(w))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	if len(res) != 3 {
+		t.Errorf("expected 3 partial results, got %d: %v", len(res), res)
+	}
+}
+
+func TestFindTemplates(t *testing.T) {
+	root := makeTree(t, "a/1.txt", "a/2.log", "b/README.md", "3.txt")
+
+	strs := []string{"*.txt", "README*"}
+	ts := NewTemplates(strs)
+
+	want := mustFind(t, root, strs, Recursively)
+
+	for i := 0; i < 2; i++ {
+		res, err := FindTemplates(context.Background(), root, ts, Recursively)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertPaths(t, relPaths(t, root, res), want...)
+	}
+
+	// Copies of the templates are lowercased the same way as the names.
+	res, err := FindTemplates(context.Background(), root, ts,
+		Recursively, Insensitive,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "3.txt", "a/1.txt", "b/README.md")
+
+	res, err = FindTemplates(context.Background(), root, NewTemplates([]string{"*.TXT"}),
+		Recursively, Insensitive,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "3.txt", "a/1.txt")
+
+	if !ts[1].Match("README.md") {
+		t.Errorf("template was changed: %s", ts[1])
+	}
+
+	res, err = FindTemplates(context.Background(), root, nil, Recursively)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res)
+}
+
+func TestWithOptions(t *testing.T) {
+	root := makeTree(t, "a/b/", "a/1.txt", "c/", "2.txt")
+
+	recursiveFolders := Options{Recursively, Only(Folder)}
+	names := Options{Name}
+
+	got := mustFind(t, root, "*", WithOptions(recursiveFolders))
+	assertPaths(t, got, "a", "a/b", "c")
+
+	res, err := Find(context.Background(), root, "*",
+		WithOptions(recursiveFolders), WithOptions(names),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, "a", "b", "c")
+}
+
+func TestWithContent(t *testing.T) {
+	root := makeTree(t, "a/", "empty.txt")
+
+	files := map[string]string{
+		"1.txt":   "first line\nTODO: fix me\n",
+		"2.txt":   "nothing to do here\n",
+		"a/3.txt": strings.Repeat("x", 1<<16) + "\ntodo: later\n",
+		"a/4.log": "TODO: not a text file\n",
+	}
+
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := mustFind(t, root, "*.txt",
+		Recursively, WithContent(regexp.MustCompile(`(?i)todo:`)),
+	)
+	assertPaths(t, got, "1.txt", "a/3.txt")
+
+	// Folders have no content.
+	got = mustFind(t, root, "*",
+		Recursively, WithContent(regexp.MustCompile(`.*`)),
+	)
+	assertPaths(t, got, "1.txt", "2.txt", "a/3.txt", "a/4.log", "empty.txt")
+}
+
+func TestWithNullDelimiter(t *testing.T) {
+	root := makeTree(t, "a.txt", "b\nc.txt", "d.md")
+
+	var buf bytes.Buffer
+
+	res, err := Find(context.Background(), root, "*.txt",
+		WithWriter(&buf), WithNullDelimiter,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "a.txt", "b\nc.txt")
+
+	if want := strings.Join(res, "\x00") + "\x00"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWithJSONOutput(t *testing.T) {
+	root := makeTree(t, "a/", "b/")
+
+	err := os.WriteFile(filepath.Join(root, "a", "1.txt"), []byte("abc"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	res, err := Find(context.Background(), root, "*",
+		Recursively, WithJSONOutput, WithWriter(&buf),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "a", "a/1.txt", "b")
+	slices.Sort(res)
+
+	want := map[string]jsonResult{
+		res[0]: {Path: res[0], Size: -1, IsDir: true},
+		res[1]: {Path: res[1], Size: 3},
+		res[2]: {Path: res[2], Size: -1, IsDir: true},
+	}
+
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var got jsonResult
+		if err := dec.Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		w, ok := want[got.Path]
+		if !ok {
+			t.Fatalf("unexpected result: %+v", got)
+		}
+
+		// Size of the folder depends on the file system.
+		if w.IsDir {
+			w.Size = got.Size
+		}
+
+		if got != w {
+			t.Errorf("got %+v, want %+v", got, w)
+		}
+
+		delete(want, got.Path)
+	}
+
+	if len(want) != 0 {
+		t.Errorf("missing results: %v", want)
+	}
+}
+
+func TestMatchFullPath_doubleStar(t *testing.T) {
+	root := makeTree(t,
+		"src/test.go", "src/a/test.go", "src/a/b/test.go", "src/a/main.go",
+		"other/test.go",
+	)
+
+	got := mustFind(t, root, "src/**/test.go", Recursively, MatchFullPath)
+	assertPaths(t, got, "src/a/b/test.go", "src/a/test.go", "src/test.go")
+
+	got = mustFind(t, root, "src/*/test.go", Recursively, MatchFullPath)
+	assertPaths(t, got, "src/a/test.go")
+
+	got = mustFind(t, root, "src/**", Recursively, MatchFullPath, Only(File))
+	assertPaths(t, got,
+		"src/a/b/test.go", "src/a/main.go", "src/a/test.go", "src/test.go",
+	)
+}
+
+func TestIncludeRoot(t *testing.T) {
+	parent := makeTree(t, "data/data/", "data/other/", "data/1.txt")
+	root := filepath.Join(parent, "data")
+
+	got := mustFind(t, root, "data", Recursively, Only(Folder))
+	assertPaths(t, got, "data")
+
+	res, err := Find(context.Background(), root, "data",
+		Recursively, Only(Folder), IncludeRoot,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, root, filepath.Join(root, "data"))
+
+	// Root is not a file.
+	got = mustFind(t, root, "data", Only(File), IncludeRoot)
+	assertPaths(t, got)
+
+	// Name of the relative root is the resolved one.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	res, err = Find(context.Background(), ".", "*", IncludeRoot, Name, Only(Folder))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, "data", "data", "other")
+}
+
+func TestFind_fileRoot(t *testing.T) {
+	root := makeTree(t, "a/1.txt", "2.md")
+
+	tests := []struct {
+		where string
+		want  []string
+	}{
+		{filepath.Join(root, "a", "1.txt"), []string{"a/1.txt"}},
+		{filepath.Join(root, "2.md"), nil},
+		{filepath.Join(root, "a"), []string{"a/1.txt"}},
+	}
+
+	for _, tt := range tests {
+		res, err := Find(context.Background(), tt.where, "*.txt", Recursively)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertPaths(t, relPaths(t, root, res), tt.want...)
+	}
+
+	res, err := FindMany(context.Background(),
+		[]string{filepath.Join(root, "2.md"), filepath.Join(root, "a")},
+		"*", Name,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, "2.md", "1.txt")
+}
+
+func TestWithProgress(t *testing.T) {
+	root := makeWideTree(t, 3, 2)
+
+	for _, workers := range []int{1, 4} {
+		var calls []ProgressStats
+
+		res, err := Find(context.Background(), root, "*",
+			Recursively, Concurrency(workers),
+			WithProgress(func(stats ProgressStats) {
+				calls = append(calls, stats)
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Root, 3 folders in it and the final call.
+		if len(calls) != 1+3+1 {
+			t.Fatalf("unexpected number of calls: %d", len(calls))
+		}
+
+		for i := 1; i < len(calls); i++ {
+			prev, cur := calls[i-1], calls[i]
+			if cur.DirsScanned < prev.DirsScanned ||
+				cur.EntriesScanned < prev.EntriesScanned ||
+				cur.Matches < prev.Matches {
+				t.Fatalf("stats decreased: %+v -> %+v", prev, cur)
+			}
+		}
+
+		last := calls[len(calls)-1]
+		if last.DirsScanned != 4 || last.Matches != len(res) ||
+			last.EntriesScanned != len(res) {
+			t.Errorf("unexpected final stats: %+v, found %d", last, len(res))
+		}
+	}
+}
+
+// countingFS counts calls to the file system.
+type countingFS struct {
+	osFS
+	calls *atomic.Int64
+}
+
+func (c countingFS) Open(name string) (fs.File, error) {
+	c.calls.Add(1)
+
+	return c.osFS.Open(name)
+}
+
+func (c countingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	c.calls.Add(1)
+
+	return c.osFS.ReadDir(name)
+}
+
+func (c countingFS) Stat(name string) (fs.FileInfo, error) {
+	c.calls.Add(1)
+
+	return c.osFS.Stat(name)
+}
+
+func (c countingFS) Resolve(name string) (string, error) {
+	c.calls.Add(1)
+
+	return c.osFS.Resolve(name)
+}
+
+func BenchmarkFind_deep(b *testing.B) {
+	var paths []string
+
+	prefix := ""
+	for i := 0; i < 50; i++ {
+		prefix += fmt.Sprintf("d%d/", i)
+		paths = append(paths, prefix+"file.txt")
+	}
+
+	root := makeTree(b, paths...)
+
+	var calls atomic.Int64
+
+	withCounter := func(o *options) { o.fsys = countingFS{calls: &calls} }
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := Find(context.Background(), root, "*.txt",
+			Recursively, withCounter,
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	// Only folders are read, entries and root are not checked again.
+	b.ReportMetric(float64(calls.Load())/float64(b.N), "calls/op")
+}
+
+// memReader reads folders under root from the in-memory fsys.
+func memReader(root string, fsys fs.FS) dirReader {
+	return func(name string) ([]fs.DirEntry, error) {
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return nil, err
+		}
+
+		return fs.ReadDir(fsys, filepath.ToSlash(rel))
+	}
+}
+
+// memDeepTree returns in-memory tree of depth folders with a single
+// file in each of them and paths of the files.
+func memDeepTree(depth int) (fstest.MapFS, []string) {
+	fsys := fstest.MapFS{}
+
+	var files []string
+
+	prefix := ""
+	for i := 0; i < depth; i++ {
+		prefix += fmt.Sprintf("d%d/", i)
+		files = append(files, prefix+"file.txt")
+		fsys[prefix+"file.txt"] = &fstest.MapFile{}
+	}
+
+	return fsys, files
+}
+
+func TestWithDirReader(t *testing.T) {
+	root := t.TempDir()
+	fsys, files := memDeepTree(200)
+
+	res := mustFind(t, root, "*.txt", Recursively, withDirReader(memReader(root, fsys)))
+
+	slices.Sort(files)
+	assertPaths(t, res, files...)
+
+	t.Run("error", func(t *testing.T) {
+		fail := func(name string) ([]fs.DirEntry, error) {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrPermission}
+		}
+
+		_, err := Find(context.Background(), root, "*.txt", withDirReader(fail))
+		if !errors.Is(err, fs.ErrPermission) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func BenchmarkFind_memDeep(b *testing.B) {
+	root := b.TempDir()
+	fsys, _ := memDeepTree(200)
+	reader := withDirReader(memReader(root, fsys))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, err := Find(context.Background(), root, "*.txt", Recursively, reader)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMax(t *testing.T) {
+	root := makeWideTree(t, 3, 3)
+
+	for _, workers := range []int{1, 4} {
+		for _, max := range []int{1, 2, 3, 4, 7, 12, 20} {
+			got := mustFind(t, root, "*",
+				Recursively, Max(max), Concurrency(workers),
+			)
+			if len(got) != max {
+				t.Errorf("workers %d: Max(%d) returned %d results: %v",
+					workers, max, len(got), got)
+			}
+
+			outCh, errCh := FindWithIterator(context.Background(), root, "*",
+				Recursively, Max(max), Concurrency(workers),
+			)
+
+			res, err := collect(outCh, errCh)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(res) != max {
+				t.Errorf("workers %d: iterator with Max(%d) returned %d results",
+					workers, max, len(res))
+			}
+		}
+	}
+
+	// Limit larger than the number of entries returns all of them.
+	if got := mustFind(t, root, "*", Recursively, Max(100)); len(got) != 51 {
+		t.Errorf("expected all 51 entries, got %d", len(got))
+	}
+}
+
+func TestInsensitive_keepsCase(t *testing.T) {
+	parent := makeTree(t, "Data/Src/Main.GO", "Data/Src/util.go", "Data/README.md")
+	root := filepath.Join(parent, "Data")
+
+	got := mustFind(t, root, "*.go", Recursively, Insensitive)
+	assertPaths(t, got, "Src/Main.GO", "Src/util.go")
+
+	got = mustFind(t, root, "*src/main*", Recursively, Insensitive, MatchFullPath)
+	assertPaths(t, got, "Src/Main.GO")
+
+	res, err := Find(context.Background(), root, "MAIN.go",
+		Recursively, Insensitive, Name,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, "Main.GO")
+
+	info, err := FindInfo(context.Background(), root, "readme.MD", Insensitive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(info) != 1 || info[0].Info.Name() != "README.md" ||
+		filepath.Base(info[0].Path) != "README.md" {
+		t.Errorf("unexpected results: %+v", info)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(parent); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	res, err = Find(context.Background(), "Data", "*.GO",
+		Recursively, Insensitive, RelativePaths,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res,
+		filepath.Join("Data", "Src", "Main.GO"),
+		filepath.Join("Data", "Src", "util.go"),
+	)
+}
+
+func TestWithErrorHandler(t *testing.T) {
+	root := makeTree(t, "a.txt", "b.txt", "c.txt", "sub/d.txt", "bad/e.txt")
+
+	tests := []struct {
+		name   string
+		ret    error
+		want   []string
+		failed []string
+	}{
+		{
+			name:   "continue",
+			want:   []string{"a.txt", "c.txt", "sub/d.txt"},
+			failed: []string{"b.txt", "bad"},
+		},
+		{
+			name:   "skip dir",
+			ret:    ErrSkipDir,
+			want:   []string{"a.txt"},
+			failed: []string{"b.txt"},
+		},
+		{
+			name:   "abort",
+			ret:    fs.ErrPermission,
+			failed: []string{"b.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failed []string
+
+			res, err := Find(context.Background(), root, "*.txt",
+				Recursively,
+				WithContent(regexp.MustCompile(`.*`)),
+				withUnreadable("b.txt", "bad"),
+				WithErrorHandler(func(path string, err error) error {
+					failed = append(failed, filepath.Base(path))
+
+					if tt.ret == fs.ErrPermission {
+						return err
+					}
+
+					return tt.ret
+				}),
+			)
+			if tt.ret == fs.ErrPermission {
+				if !errors.Is(err, fs.ErrPermission) {
+					t.Fatalf("expected permission error, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			assertPaths(t, relPaths(t, root, res), tt.want...)
+
+			if !slices.Equal(failed, tt.failed) {
+				t.Errorf("got errors for %q, want %q", failed, tt.failed)
+			}
+		})
+	}
+}
+
+func TestMatchFullPath_segment(t *testing.T) {
+	root := makeTree(t,
+		"test/a.go", "test/sub/b.go", "mytest/c.go", "tests/d.go", "e.go",
+	)
+
+	got := mustFind(t, root, "/test/", Recursively, MatchFullPath, Only(File))
+	assertPaths(t, got, "test/a.go", "test/sub/b.go")
+
+	got = mustFind(t, root, "/test/", Recursively, Only(Folder))
+	assertPaths(t, got, "test")
+}
+
+func TestNestedAnd(t *testing.T) {
+	root := makeTree(t,
+		"my-project/main.go", "my-project/cmd/main.go", "my-project/cmd/util.go",
+		"other/main.go", "other/project/readme.md", "project-main.go",
+	)
+
+	got := mustFind(t, root, "*project*&*main.go*", Recursively)
+	assertPaths(t, got, "project-main.go")
+
+	got = mustFind(t, root, "*project*&*main.go*", Recursively, NestedAnd)
+	assertPaths(t, got, "my-project/cmd/main.go", "my-project/main.go")
+
+	got = mustFind(t, root, "*project*&cmd&*.go", Recursively, NestedAnd)
+	assertPaths(t, got, "my-project/cmd/main.go", "my-project/cmd/util.go")
+
+	got = mustFind(t, root, "(other|cmd)&!util.go", Recursively, NestedAnd, Only(File))
+	assertPaths(t, got, "my-project/cmd/main.go", "other/main.go", "other/project/readme.md")
+
+	// Templates without chain match names as usual.
+	got = mustFind(t, root, []string{"*.md", "project&*"}, Recursively, NestedAnd)
+	assertPaths(t, got, "other/project/readme.md")
+
+	// Chains inside '|' and parentheses are nested as well.
+	got = mustFind(t, root, "readme*|*project*&main.go", Recursively, NestedAnd)
+	assertPaths(t, got, "my-project/cmd/main.go", "my-project/main.go", "other/project/readme.md")
+
+	got = mustFind(t, root, "(cmd&util.go)|(other&main.go)", Recursively, NestedAnd)
+	assertPaths(t, got, "my-project/cmd/util.go", "other/main.go")
+
+	got = mustFind(t, root, "(*project*&cmd)&*.go", Recursively, NestedAnd)
+	assertPaths(t, got, "my-project/cmd/main.go", "my-project/cmd/util.go")
+
+	res, err := FindTemplates(context.Background(), root, Templates{
+		Or(And(NewTemplate("other"), NewTemplate("main.go")), NewTemplate("util.go")),
+	}, Recursively, NestedAnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "my-project/cmd/util.go", "other/main.go")
+
+	// Templates without chain match the same string as without nesting,
+	// folders of the root are not matched by the chains.
+	root2 := makeTree(t, "src/main.go", "proj/src/util.go", "proj/cmd/main.go")
+
+	for _, opt := range []optFunc{MatchFullPath, MatchRelativePath} {
+		want := mustFind(t, root2, []string{"src/*.go", "**/cmd/*"}, Recursively, opt)
+		if len(want) == 0 {
+			t.Fatal("expected matches without NestedAnd")
+		}
+
+		got = mustFind(t, root2, []string{"src/*.go", "**/cmd/*"}, Recursively, opt, NestedAnd)
+		assertPaths(t, got, want...)
+
+		got = mustFind(t, root2, "proj&src&*.go", Recursively, opt, NestedAnd)
+		assertPaths(t, got, "proj/src/util.go")
+
+		got = mustFind(t, root2, "*&*", Recursively, opt, NestedAnd, Only(File))
+		assertPaths(t, got, "proj/cmd/main.go", "proj/src/util.go", "src/main.go")
+
+		got = mustFind(t, root2, filepath.Base(root2)+"&*.go", Recursively, opt, NestedAnd)
+		assertPaths(t, got)
+	}
+
+	// Folders, which cannot fit the rest of the chain, are skipped.
+	var buf bytes.Buffer
+
+	got = mustFind(t, root, "*project*&*main.go*",
+		Recursively, NestedAnd, MaxDepth(1), WithTrace(&buf),
+	)
+	assertPaths(t, got, "my-project/main.go")
+
+	trace := buf.String()
+	if !strings.Contains(trace, "prune: "+filepath.Join(root, "other")+" (nested templates)") ||
+		strings.Contains(trace, "prune: "+filepath.Join(root, "my-project")+" (") {
+		t.Errorf("unexpected trace:\n%s", trace)
+	}
+}
+
+func TestMaxPerDir(t *testing.T) {
+	var paths []string
+	for i := 0; i < 20; i++ {
+		paths = append(paths, fmt.Sprintf("wide/%02d.txt", i))
+	}
+
+	root := makeTree(t, append(paths, "a.txt", "b.txt", "c.txt", "sub/d.txt")...)
+
+	got := mustFind(t, root, "*.txt", Recursively, MaxPerDir(2))
+	assertPaths(t, got, "a.txt", "b.txt", "sub/d.txt", "wide/00.txt", "wide/01.txt")
+
+	// Global limit still applies.
+	got = mustFind(t, root, "*.txt", Recursively, MaxPerDir(2), Max(3))
+	if len(got) != 3 {
+		t.Errorf("expected 3 results, got %v", got)
+	}
+
+	got = mustFind(t, root, "*.txt", Recursively, MaxPerDir(0))
+	if len(got) != 24 {
+		t.Errorf("expected all 24 results, got %d", len(got))
+	}
+}
+
+func TestMatchRelativePath(t *testing.T) {
+	// Root itself is located in the folder 'sub'.
+	for _, prefix := range []string{"sub/", "one/two/sub/"} {
+		root := filepath.Join(makeTree(t,
+			prefix+"sub/a.go", prefix+"sub/deep/b.go", prefix+"c.go",
+		), filepath.FromSlash(prefix))
+
+		got := mustFind(t, root, "sub/*.go", Recursively, MatchRelativePath)
+		assertPaths(t, got, "sub/a.go")
+
+		got = mustFind(t, root, "c.go", Recursively, MatchRelativePath)
+		assertPaths(t, got, "c.go")
+
+		// Absolute path contains the root folder as well.
+		got = mustFind(t, root, "sub/*.go", Recursively, MatchFullPath)
+		assertPaths(t, got, "c.go", "sub/a.go")
+	}
+
+	fsys := fstest.MapFS{
+		"data/sub/a.go": &fstest.MapFile{},
+		"data/b.go":     &fstest.MapFile{},
+	}
+
+	res, err := FindFS(context.Background(), fsys, "data", "sub/*.go",
+		Recursively, MatchRelativePath,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, "data/sub/a.go")
+}
+
+func TestTraversalOrder(t *testing.T) {
+	parent := makeTree(t, "root/a/b/1.txt", "root/a/2.txt", "root/c/")
+	root := filepath.Join(parent, "root")
+
+	pos := func(res []string) map[string]int {
+		m := make(map[string]int, len(res))
+		for i, p := range res {
+			rel, err := filepath.Rel(parent, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m[filepath.ToSlash(rel)] = i
+		}
+
+		return m
+	}
+
+	for _, order := range []Order{PreOrder, PostOrder} {
+		res, err := Find(context.Background(), root, "*",
+			Recursively, IncludeRoot, TraversalOrder(order), Concurrency(4),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(res) != 6 {
+			t.Fatalf("unexpected results: %v", res)
+		}
+
+		i := pos(res)
+
+		pairs := [][2]string{
+			{"root", "root/a"},
+			{"root", "root/c"},
+			{"root/a", "root/a/b"},
+			{"root/a", "root/a/2.txt"},
+			{"root/a/b", "root/a/b/1.txt"},
+		}
+
+		for _, p := range pairs {
+			parentFirst := i[p[0]] < i[p[1]]
+			if parentFirst != (order == PreOrder) {
+				t.Errorf("order %d: %q at %d, %q at %d",
+					order, p[0], i[p[0]], p[1], i[p[1]])
+			}
+		}
+	}
+}
+
+func TestOnlySymlink(t *testing.T) {
+	root := makeTree(t, "dir/file", "file")
+
+	symlink(t, filepath.Join(root, "dir"), filepath.Join(root, "dir-link"))
+	symlink(t, filepath.Join(root, "file"), filepath.Join(root, "dir", "file-link"))
+	symlink(t, filepath.Join(root, "missing"), filepath.Join(root, "broken"))
+
+	got := mustFind(t, root, "*", Recursively, Only(Symlink))
+	assertPaths(t, got, "broken", "dir-link", "dir/file-link")
+
+	got = mustFind(t, root, "*", Recursively, Only(Symlink), FollowSymlinks)
+	assertPaths(t, got,
+		"broken", "dir-link", "dir-link/file-link", "dir/file-link",
+	)
+
+	got = mustFind(t, root, "*", Recursively, Only(File))
+	assertPaths(t, got, "broken", "dir-link", "dir/file", "dir/file-link", "file")
+}
+
+func TestBrokenSymlinks(t *testing.T) {
+	root := makeTree(t, "file", "dir/")
+
+	symlink(t, filepath.Join(root, "file"), filepath.Join(root, "valid"))
+	symlink(t, filepath.Join(root, "missing"), filepath.Join(root, "dir", "dangling"))
+
+	got := mustFind(t, root, "*", Recursively, BrokenSymlinks)
+	assertPaths(t, got, "dir/dangling")
+
+	got = mustFind(t, root, "valid|file", Recursively, BrokenSymlinks)
+	assertPaths(t, got)
+}
+
+func TestFindMatches(t *testing.T) {
+	root := makeTree(t, "main.go", "main_test.go", "README.md", "notes.txt")
+
+	templates := []string{"*_test.go", "*.go", "*.md"}
+
+	res, err := FindMatches(context.Background(), root, templates, Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]string, len(res))
+	for _, m := range res {
+		got[m.Path] = m.Matched.String()
+	}
+
+	// Test file matches both '*_test.go' and '*.go', the first one wins.
+	want := map[string]string{
+		"main.go":      "*.go",
+		"main_test.go": "*_test.go",
+		"README.md":    "*.md",
+	}
+
+	if !maps.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	ts := NewTemplates(templates)
+	if i := MatchIndex(ts, "notes.txt"); i != -1 {
+		t.Errorf("unexpected index for notes.txt: %d", i)
+	}
+}
+
+func TestWithTrace(t *testing.T) {
+	root := makeTree(t, "src/a/b/1.go", "vendor/2.go", ".git/3", "4.go")
+
+	var buf bytes.Buffer
+
+	got := mustFind(t, root, "*.go",
+		Recursively, Exclude("vendor"), SkipHidden, MaxDepth(1), WithTrace(&buf),
+	)
+	assertPaths(t, got, "4.go")
+
+	want := []string{
+		"enter: " + root,
+		"prune: " + filepath.Join(root, ".git") + " (hidden)",
+		"enter: " + filepath.Join(root, "src"),
+		"prune: " + filepath.Join(root, "src", "a") + " (max depth)",
+		"prune: " + filepath.Join(root, "vendor") + " (excluded)",
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !slices.Equal(lines, want) {
+		t.Errorf("got trace:\n%s\nwant:\n%s", buf.String(), strings.Join(want, "\n"))
+	}
+}
+
+func TestWithMatchFunc(t *testing.T) {
+	root := makeTree(t, "main_test.go", "main.go", "test.md", "notes.txt")
+
+	atLeast := func(n int) MatchFunc {
+		return func(ts Templates, str string) bool {
+			matched := 0
+			for _, t := range ts {
+				if t.Match(str) {
+					matched++
+				}
+			}
+
+			return matched >= n
+		}
+	}
+
+	templates := []string{"*test*", "*.go", "main*"}
+
+	got := mustFind(t, root, templates, WithMatchFunc(atLeast(2)))
+	assertPaths(t, got, "main.go", "main_test.go")
+
+	got = mustFind(t, root, templates, WithMatchFunc(atLeast(3)))
+	assertPaths(t, got, "main_test.go")
+
+	got = mustFind(t, root, templates, WithMatchFunc(MatchAny))
+	assertPaths(t, got, "main.go", "main_test.go", "test.md")
+
+	got = mustFind(t, root, templates, Strict, WithMatchFunc(nil))
+	assertPaths(t, got, "main.go", "main_test.go", "test.md")
+}
+
+func TestPlatformCase(t *testing.T) {
+	if got := platformCase("windows")("Main.GO"); got != "main.go" {
+		t.Errorf("windows should be case insensitive, got %q", got)
+	}
+
+	if got := platformCase("linux")("Main.GO"); got != "Main.GO" {
+		t.Errorf("linux should be case sensitive, got %q", got)
+	}
+
+	root := makeTree(t, "Main.GO", "util.go")
+
+	got := mustFind(t, root, "*.go", Insensitive, Sensitive)
+	assertPaths(t, got, "util.go")
+}
+
+func TestTarget_windowsSeparator(t *testing.T) {
+	defer func(sep string) { pathSeparator = sep }(pathSeparator)
+
+	pathSeparator = `\`
+
+	opt := defaultOptions()
+	opt.caseFunc = platformCase("windows")
+
+	if got := opt.target(`C:\Src\Main.go`); got != "main.go" {
+		t.Errorf("unexpected name: %q", got)
+	}
+
+	MatchFullPath(opt)
+
+	target := opt.target(`C:\Src\Main.go`)
+	if target != "c:/src/main.go" {
+		t.Errorf("unexpected full path: %q", target)
+	}
+
+	if !NewTemplate("src/*.go").Match(target) {
+		t.Errorf("template should match %q", target)
+	}
+}
+
+func TestWithBufferedWriter(t *testing.T) {
+	root := makeWideTree(t, 5, 2)
+
+	var (
+		buf    bytes.Buffer
+		writes int
+	)
+
+	w := writerFunc(func(p []byte) (int, error) {
+		writes++
+
+		return buf.Write(p)
+	})
+
+	res, err := Find(context.Background(), root, "*",
+		Recursively, WithBufferedWriter(w, 1<<16),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := strings.Join(res, "\n") + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	if writes != 1 {
+		t.Errorf("expected single write, got %d", writes)
+	}
+
+	// Output is flushed on cancellation as well.
+	buf.Reset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var visited []string
+
+	_, err = Find(ctx, root, "*",
+		Recursively, WithBufferedWriter(&buf, 1<<16),
+		WithVisitor(func(path string) error {
+			if visited = append(visited, path); len(visited) == 3 {
+				cancel()
+			}
+
+			return nil
+		}),
+	)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	if want := strings.Join(visited, "\n") + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	root := makeTree(t,
+		"a/1.go", "a/2.txt", "skip/3.go", "z/4.go", "z/5.go", "z/6.go",
+		".hidden/7.go",
+	)
+
+	type call struct {
+		path    string
+		matched bool
+	}
+
+	walk := func(fn func(rel string, d fs.DirEntry) error) []call {
+		var calls []call
+
+		err := Walk(context.Background(), root, "*.go",
+			func(path string, d fs.DirEntry, matched bool) error {
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+
+				rel = filepath.ToSlash(rel)
+				calls = append(calls, call{rel, matched})
+
+				return fn(rel, d)
+			},
+			Recursively, SkipHidden,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return calls
+	}
+
+	got := walk(func(rel string, d fs.DirEntry) error {
+		switch {
+		case rel == "skip" && d.IsDir():
+			return fs.SkipDir
+		case rel == "z/4.go":
+			// Skips the rest of the folder.
+			return fs.SkipDir
+		default:
+			return nil
+		}
+	})
+
+	want := []call{
+		{"a", false}, {"a/1.go", true}, {"a/2.txt", false},
+		{"skip", false},
+		{"z", false}, {"z/4.go", true},
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got = walk(func(rel string, _ fs.DirEntry) error {
+		if rel == "a/1.go" {
+			return fs.SkipAll
+		}
+
+		return nil
+	})
+
+	if want := []call{{"a", false}, {"a/1.go", true}}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	errStop := errors.New("stop")
+
+	err := Walk(context.Background(), root, "*",
+		func(string, fs.DirEntry, bool) error { return errStop },
+	)
+	if !errors.Is(err, errStop) {
+		t.Errorf("expected callback error, got %v", err)
+	}
+}
+
+func TestWalk_maxPerDir(t *testing.T) {
+	root := makeTree(t, "1.go", "2.go", "3.go", "4.txt")
+
+	var got []string
+
+	err := Walk(context.Background(), root, "*.go",
+		func(path string, _ fs.DirEntry, matched bool) error {
+			got = append(got, fmt.Sprintf("%s:%t", filepath.Base(path), matched))
+
+			return nil
+		},
+		MaxPerDir(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries over the limit are still passed, but not matched.
+	want := []string{"1.go:true", "2.go:true", "3.go:false", "4.txt:false"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExactName(t *testing.T) {
+	root := makeTree(t, "test/main.go", "sub/test", "a*b", "ab", "contest")
+
+	// By default template matches path components with MatchFullPath.
+	got := mustFind(t, root, "test", Recursively, MatchFullPath)
+	assertPaths(t, got, "sub/test", "test", "test/main.go")
+
+	got = mustFind(t, root, "test", Recursively, MatchFullPath, ExactName)
+	assertPaths(t, got, "sub/test", "test")
+
+	// Wildcards are matched literally.
+	got = mustFind(t, root, "a*b", Recursively)
+	assertPaths(t, got, "a*b", "ab")
+
+	got = mustFind(t, root, "a*b", Recursively, ExactName)
+	assertPaths(t, got, "a*b")
+
+	got = mustFind(t, root, []string{"AB", "Contest"},
+		Recursively, ExactName, Insensitive,
+	)
+	assertPaths(t, got, "ab", "contest")
+
+	// Repeated name is not equal to the template.
+	root = makeTree(t, "ab", "abab", "test", "testtest")
+
+	got = mustFind(t, root, []string{"ab", "test"}, ExactName)
+	assertPaths(t, got, "ab", "test")
+}
+
+func TestGlobMode(t *testing.T) {
+	root := makeTree(t, "file1.txt", "file2.txt", "fileA.txt", "file10.txt", "sub/file3.txt")
+
+	got := mustFind(t, root, "file[0-9].txt", Recursively, GlobMode)
+	assertPaths(t, got, "file1.txt", "file2.txt", "sub/file3.txt")
+
+	got = mustFind(t, root, "file[^0-9].txt", Recursively, GlobMode)
+	assertPaths(t, got, "fileA.txt")
+
+	got = mustFind(t, root, "file?.txt", GlobMode)
+	assertPaths(t, got, "file1.txt", "file2.txt", "fileA.txt")
+
+	got = mustFind(t, root, "FILE??.TXT", GlobMode, Insensitive)
+	assertPaths(t, got, "file10.txt")
+
+	_, err := Find(context.Background(), root, "file[0-9.txt", GlobMode)
+	if !errors.Is(err, path.ErrBadPattern) {
+		t.Errorf("want %v, got: %v", path.ErrBadPattern, err)
+	}
+}
+
+func TestFindN(t *testing.T) {
+	root := makeWideTree(t, 3, 2)
+
+	for _, tc := range []struct {
+		n    int
+		want int
+	}{
+		{0, 0},
+		{5, 5},
+		{100, 15},
+	} {
+		for _, opts := range []Options{
+			{Recursively},
+			{Recursively, Concurrency(4)},
+		} {
+			res, err := FindN(context.Background(), root, "*", tc.n, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(res) != tc.want {
+				t.Errorf("FindN(%d): got %d results, want %d", tc.n, len(res), tc.want)
+			}
+		}
+	}
+
+	_, err := FindN(context.Background(), root, "*", -1)
+	if !errors.Is(err, ErrNegativeLimit) {
+		t.Errorf("want %v, got: %v", ErrNegativeLimit, err)
+	}
+}
+
+func TestFindDirsContaining(t *testing.T) {
+	root := makeTree(t,
+		"Makefile", "a/Makefile", "a/main.c", "a/b/main.c",
+		"c/d/Makefile", "c/d/e/", "f/Makefile/",
+	)
+
+	for _, opts := range []Options{nil, {Concurrency(4)}} {
+		res, err := FindDirsContaining(context.Background(), root, "Makefile", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertPaths(t, relPaths(t, root, res), ".", "a", "c/d", "f")
+	}
+
+	res, err := FindDirsContaining(context.Background(), root, "Makefile", Only(File))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, relPaths(t, root, res), ".", "a", "c/d")
+
+	res, err = FindDirsContaining(context.Background(), root, "*.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "a", "a/b")
+}
+
+// slowFS delays reading of each folder.
+type slowFS struct {
+	osFS
+	delay time.Duration
+}
+
+func (s slowFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	time.Sleep(s.delay)
+
+	return s.osFS.ReadDir(name)
+}
+
+func TestWithTimeout(t *testing.T) {
+	root := makeWideTree(t, 3, 3)
+	slow := func(o *options) { o.fsys = slowFS{delay: 20 * time.Millisecond} }
+
+	res, err := Find(context.Background(), root, "*",
+		Recursively, slow, WithTimeout(50*time.Millisecond),
+	)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	if len(res) == 0 || len(res) >= 51 {
+		t.Errorf("expected partial results, got %d", len(res))
+	}
+
+	// Parent context is still respected.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = Find(ctx, root, "*", Recursively, WithTimeout(time.Hour))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	res, err = Find(context.Background(), root, "*",
+		Recursively, WithTimeout(time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 51 {
+		t.Errorf("expected 51 results, got %d", len(res))
+	}
+}
+
+func TestWithInvert(t *testing.T) {
+	root := makeTree(t, "main.go", "main_test.go", "README.md", "sub/notes.txt")
+
+	got := mustFind(t, root, []string{"*.go", "*.md"}, Recursively, WithInvert)
+	assertPaths(t, got, "sub", "sub/notes.txt")
+
+	// Only files, which fail at least one of the templates.
+	got = mustFind(t, root, []string{"main*", "*.go"},
+		Recursively, Strict, WithInvert, Only(File),
+	)
+	assertPaths(t, got, "README.md", "sub/notes.txt")
+
+	res, err := FindMatches(context.Background(), root, "*.go", WithInvert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range res {
+		if m.Matched != nil {
+			t.Errorf("unexpected template for %s: %v", m.Path, m.Matched)
+		}
+	}
+}
+
+func TestOnlyEmpty(t *testing.T) {
+	root := makeTree(t, "empty/", "full/1.txt", "full/nested/", "2.txt")
+
+	got := mustFind(t, root, "*", Recursively, Only(Folder), OnlyEmpty)
+	assertPaths(t, got, "empty", "full/nested")
+
+	got = mustFind(t, root, "*", Recursively, OnlyEmpty)
+	assertPaths(t, got, "empty", "full/nested")
+
+	_, err := Find(context.Background(), root, "*",
+		OnlyEmpty, withUnreadable("empty"),
+	)
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("want %v, got: %v", fs.ErrPermission, err)
+	}
+
+	res, err := Find(context.Background(), root, "*",
+		OnlyEmpty, withUnreadable("empty"), WithErrorsSkip,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 0 {
+		t.Errorf("unexpected results: %v", res)
+	}
+}
+
+func TestFindWithStats(t *testing.T) {
+	root := makeTree(t, "a/1.txt", "a/b/2.txt", "a/b/3.log", "c/", "4.txt")
+
+	for _, opts := range []Options{
+		{Recursively},
+		{Recursively, Concurrency(4)},
+	} {
+		res, stats, err := FindWithStats(context.Background(), root, "*.txt", opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := Stats{
+			DirsScanned:  4,
+			FilesScanned: 4,
+			Matches:      3,
+			Duration:     stats.Duration,
+		}
+
+		if stats != want {
+			t.Errorf("got %+v, want %+v", stats, want)
+		}
+
+		if len(res) != stats.Matches {
+			t.Errorf("got %d results, want %d", len(res), stats.Matches)
+		}
+
+		if stats.Duration <= 0 {
+			t.Errorf("unexpected duration: %v", stats.Duration)
+		}
+	}
+}
+
+func TestWithReadBudget(t *testing.T) {
+	root := makeTree(t)
+
+	// Each file is 100 bytes long with the match at the end.
+	for _, name := range []string{"1.txt", "2.txt", "3.txt"} {
+		content := strings.Repeat("x", 94) + "\nTODO\n"
+
+		err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	todo := WithContent(regexp.MustCompile(`TODO`))
+
+	got := mustFind(t, root, "*.txt", todo, WithReadBudget(1000))
+	assertPaths(t, got, "1.txt", "2.txt", "3.txt")
+
+	got = mustFind(t, root, "*.txt", todo, WithReadBudget(300))
+	assertPaths(t, got, "1.txt", "2.txt", "3.txt")
+
+	got = mustFind(t, root, "*.txt", todo, WithReadBudget(250))
+	assertPaths(t, got, "1.txt", "2.txt")
+
+	got = mustFind(t, root, "*.txt", todo, WithReadBudget(0))
+	assertPaths(t, got)
+}
+
+func TestWithCaseFunc(t *testing.T) {
+	root := makeTree(t, "Café.txt", "naïve.txt", "resume.txt", "Résumé.md")
+
+	// Strips accents and lowercases names and templates.
+	fold := strings.NewReplacer("é", "e", "É", "e", "ï", "i").Replace
+	fn := func(s string) string { return strings.ToLower(fold(s)) }
+
+	got := mustFind(t, root, "cafe*", WithCaseFunc(fn))
+	assertPaths(t, got, "Café.txt")
+
+	got = mustFind(t, root, []string{"RÉSUMÉ*", "naive.txt"}, WithCaseFunc(fn))
+	assertPaths(t, got, "Résumé.md", "naïve.txt", "resume.txt")
+
+	got = mustFind(t, root, "cafe*", WithCaseFunc(nil))
+	assertPaths(t, got)
+}
+
+func TestWithCaseFunc_fullPath(t *testing.T) {
+	root := makeTree(t, "Test/A.go", "Test/sub/B.go", "MyTest/C.go")
+
+	got := mustFind(t, root, "test/*.go", Recursively, MatchFullPath, Insensitive)
+	assertPaths(t, got, "Test/A.go")
+
+	// Separators must stay borders even if fn changes them.
+	fn := func(s string) string {
+		return strings.ToLower(strings.ReplaceAll(s, "/", ":"))
+	}
+
+	got = mustFind(t, root, "test/*.go", Recursively, MatchFullPath, WithCaseFunc(fn))
+	assertPaths(t, got, "Test/A.go")
+
+	got = mustFind(t, root, "/test/", Recursively, MatchFullPath, WithCaseFunc(fn), Only(File))
+	assertPaths(t, got, "Test/A.go", "Test/sub/B.go")
+
+	got = mustFind(t, root, "test/**", Recursively, MatchRelativePath,
+		WithCaseFunc(fn), Only(File),
+	)
+	assertPaths(t, got, "Test/A.go", "Test/sub/B.go")
+}
+
+func TestWithContentType(t *testing.T) {
+	root := makeTree(t, "sub/")
+
+	files := map[string]string{
+		"image.png":  "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"sub/photo":  "GIF89a\x01\x00\x01\x00",
+		"notes.txt":  "just some text\n",
+		"fake.png":   "not an image\n",
+		"empty.data": "",
+	}
+
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := mustFind(t, root, "*", Recursively, WithContentType("image/"))
+	assertPaths(t, got, "image.png", "sub/photo")
+
+	got = mustFind(t, root, "*", Recursively, WithContentType("image/gif", "text/"))
+	assertPaths(t, got, "empty.data", "fake.png", "notes.txt", "sub/photo")
+
+	_, err := Find(context.Background(), root, "*",
+		WithContentType("image/"), withUnreadable("image.png"),
+	)
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("want %v, got: %v", fs.ErrPermission, err)
+	}
+
+	got = mustFind(t, root, "*",
+		WithContentType("image/"), withUnreadable("image.png"), WithErrorsSkip,
+	)
+	assertPaths(t, got)
+}
+
+func TestWithErrorsCollect(t *testing.T) {
+	root := makeTree(t, "a/1.txt", "b/2.txt", "c/3.txt", "4.txt")
+
+	res, err := Find(context.Background(), root, "*.txt",
+		Recursively, WithErrorsCollect, withUnreadable("a", "b"),
+	)
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("want %v, got: %v", fs.ErrPermission, err)
+	}
+
+	var joined interface{ Unwrap() []error }
+	if !errors.As(err, &joined) || len(joined.Unwrap()) != 2 {
+		t.Errorf("expected 2 joined errors, got: %v", err)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "4.txt", "c/3.txt")
+
+	// Without errors result is the same as usual.
+	res, err = Find(context.Background(), root, "*.txt",
+		Recursively, WithErrorsCollect,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 4 {
+		t.Errorf("expected 4 results, got: %v", res)
+	}
+}
+
+func TestWithParentDirs(t *testing.T) {
+	root := makeTree(t,
+		"go.mod", "a/go.mod", "a/b/go.mod", "a/b/main.go", "c/main.go", "d/",
+	)
+
+	got := mustFind(t, root, "go.mod", Recursively, WithParentDirs)
+	assertPaths(t, got, ".", "a", "a/b")
+
+	got = mustFind(t, root, "*.go", Recursively, WithParentDirs)
+	assertPaths(t, got, "a/b", "c")
+
+	// Several matches in the folder give it only once.
+	got = mustFind(t, root, "*", Recursively, WithParentDirs, Only(File))
+	assertPaths(t, got, ".", "a", "a/b", "c")
+
+	res, err := Find(context.Background(), root, "*.go",
+		Recursively, WithParentDirs, Name,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, "b", "c")
+
+	info, err := FindInfo(context.Background(), root, "main.go",
+		Recursively, WithParentDirs,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range info {
+		if r.Info == nil || !r.Info.IsDir() {
+			t.Errorf("expected folder info for %s, got %v", r.Path, r.Info)
+		}
+	}
+}
+
+func TestMaxScanned(t *testing.T) {
+	// Tree has 185 entries in total.
+	root := makeWideTree(t, 5, 3)
+
+	for _, opts := range []Options{
+		{Recursively},
+		{Recursively, Concurrency(4)},
+	} {
+		var stats ProgressStats
+
+		opts = append(opts, MaxScanned(50), WithProgress(func(s ProgressStats) {
+			stats = s
+		}))
+
+		res, err := Find(context.Background(), root, "missing", opts...)
+		if !errors.Is(err, ErrScanLimit) {
+			t.Fatalf("want %v, got: %v", ErrScanLimit, err)
+		}
+
+		if len(res) != 0 {
+			t.Errorf("unexpected results: %v", res)
+		}
+
+		if stats.EntriesScanned >= 185 {
+			t.Errorf("walk was not aborted: %+v", stats)
+		}
+	}
+
+	res, err := Find(context.Background(), root, "*", Recursively, MaxScanned(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 185 {
+		t.Errorf("expected 185 results, got %d", len(res))
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home := makeTree(t, "projects/a.go", "projects/b.md")
+
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("FIND_PROJECTS", filepath.Join(home, "projects"))
+
+	for _, where := range []string{
+		"~/projects",
+		"$FIND_PROJECTS",
+		"${FIND_PROJECTS}",
+		filepath.Join("~", "projects"),
+	} {
+		res, err := Find(context.Background(), where, "*.go", ExpandPath)
+		if err != nil {
+			t.Fatalf("%s: %v", where, err)
+		}
+
+		assertPaths(t, relPaths(t, home, res), "projects/a.go")
+	}
+
+	// Paths are taken literally without the option.
+	_, err := Find(context.Background(), "~/projects", "*.go")
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v, got: %v", fs.ErrNotExist, err)
+	}
+
+	res, err := FindMany(context.Background(), []string{"~", "$FIND_PROJECTS"},
+		"*.md", Recursively, ExpandPath, Unique,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, relPaths(t, home, res), "projects/b.md")
+}
+
+func TestWithOutputTemplate(t *testing.T) {
+	root := makeTree(t, "a/")
+
+	for name, content := range map[string]string{"1.txt": "abc", "a/2.txt": "hello"} {
+		err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	_, err := Find(context.Background(), root, "*.txt",
+		Recursively, RelativePaths,
+		WithOutputTemplate("{{.Size}}\t{{.Name}}\t{{.Path}}\t{{.IsDir}}"),
+		WithWriter(&buf),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"3\t1.txt\t" + filepath.Join(root, "1.txt") + "\tfalse",
+		"5\t2.txt\t" + filepath.Join(root, "a", "2.txt") + "\tfalse",
+	}
+
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	slices.Sort(got)
+
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	_, err = Find(context.Background(), root, "*", WithOutputTemplate("{{.Size"))
+	if err == nil {
+		t.Error("expected template parse error")
+	}
+}
+
+func TestMaxPerTemplate(t *testing.T) {
+	root := makeTree(t,
+		"a/1.go", "a/2.go", "a/1.md", "b/3.go", "b/2.md", "b/3.md", "c/4.md",
+	)
+
+	res, err := FindMatches(context.Background(), root, []string{"*.go", "*.md"},
+		Recursively, MaxPerTemplate(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := make(map[string]int)
+	for _, m := range res {
+		count[m.Matched.String()]++
+	}
+
+	if want := map[string]int{"*.go": 2, "*.md": 2}; !maps.Equal(count, want) {
+		t.Errorf("got %v, want %v", count, want)
+	}
+
+	// Walk stops as soon as all the templates reach the limit.
+	got := mustFind(t, root, []string{"*.go", "*.md"}, Recursively, MaxPerTemplate(1))
+	assertPaths(t, got, "a/1.go", "a/1.md")
+
+	// Path is counted for the next template, if the first one is full.
+	got = mustFind(t, root, []string{"*", "*.md"}, Recursively, MaxPerTemplate(4))
+	assertPaths(t, got, "a", "a/1.go", "a/1.md", "a/2.go", "b/2.md", "b/3.md", "c/4.md")
+
+	got = mustFind(t, root, "*.go", Recursively, MaxPerTemplate(1), WithInvert)
+	assertPaths(t, got, "a", "a/1.md", "b", "b/2.md", "b/3.md", "c", "c/4.md")
+}
+
+func TestFind_errorClasses(t *testing.T) {
+	root := makeTree(t, "a/1.txt", "2.txt")
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		where string
+		t     string
+		opts  Options
+		want  []error
+	}{
+		{
+			name:  "missing root",
+			where: filepath.Join(root, "missing"),
+			t:     "*",
+			want:  []error{ErrRootNotFound, fs.ErrNotExist},
+		},
+		{
+			name:  "root under file",
+			where: filepath.Join(root, "2.txt", "sub"),
+			t:     "*",
+			want:  []error{ErrInvalidRoot},
+		},
+		{
+			name:  "bad template",
+			where: root,
+			t:     "(a|b",
+			want:  []error{ErrTemplateSyntax},
+		},
+		{
+			name:  "bad glob",
+			where: root,
+			t:     "[a-",
+			opts:  Options{GlobMode},
+			want:  []error{ErrTemplateSyntax, path.ErrBadPattern},
+		},
+		{
+			name:  "unreadable folder",
+			where: root,
+			t:     "*",
+			opts:  Options{Recursively, withUnreadable("a")},
+			want:  []error{ErrWalk, fs.ErrPermission},
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := Find(ctx, tt.where, tt.t, tt.opts...)
+		for _, want := range tt.want {
+			if !errors.Is(err, want) {
+				t.Errorf("%s: want %v, got: %v", tt.name, want, err)
+			}
+		}
+	}
+
+	_, err := FindFS(ctx, fstest.MapFS{}, "missing", "*")
+	if !errors.Is(err, ErrRootNotFound) {
+		t.Errorf("want %v, got: %v", ErrRootNotFound, err)
+	}
+}
+
+func TestKeepVisited(t *testing.T) {
+	root := makeWideTree(t, 3, 2)
+
+	for _, opts := range []Options{
+		{Recursively},
+		{Recursively, Concurrency(4)},
+	} {
+		var visited []string
+
+		visit := WithVisitor(func(path string) error {
+			visited = append(visited, path)
+
+			return nil
+		})
+
+		res, err := Find(context.Background(), root, "*",
+			append(opts, visit, KeepVisited)...,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(res) != 15 || len(visited) != len(res) {
+			t.Errorf("got %d results and %d visited, want 15", len(res), len(visited))
+		}
+
+		slices.Sort(res)
+		slices.Sort(visited)
+
+		if !slices.Equal(res, visited) {
+			t.Errorf("results differ from visited: %v, %v", res, visited)
+		}
+	}
+
+	// Without the option visitor consumes the results.
+	res, err := Find(context.Background(), root, "*",
+		WithVisitor(func(string) error { return nil }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 0 {
+		t.Errorf("unexpected results: %v", res)
+	}
+}
+
+func TestWithFilter(t *testing.T) {
+	root := makeTree(t, "ab.txt", "abc.txt", "sub/abcde.txt", "sub/a.md")
+
+	even := WithFilter(func(path string, d fs.DirEntry) (bool, error) {
+		return !d.IsDir() && len(d.Name())%2 == 0, nil
+	})
+
+	got := mustFind(t, root, "*", Recursively, even)
+	assertPaths(t, got, "ab.txt", "sub/a.md")
+
+	// Filter is called only for the matched entries.
+	got = mustFind(t, root, "*.txt", Recursively, even)
+	assertPaths(t, got, "ab.txt")
+
+	errFilter := errors.New("filter failed")
+	fail := WithFilter(func(path string, d fs.DirEntry) (bool, error) {
+		if d.Name() == "abc.txt" {
+			return false, errFilter
+		}
+
+		return true, nil
+	})
+
+	_, err := Find(context.Background(), root, "*.txt", fail)
+	if !errors.Is(err, errFilter) {
+		t.Errorf("want %v, got: %v", errFilter, err)
+	}
+
+	got = mustFind(t, root, "*.txt", Recursively, fail, WithErrorsSkip)
+	assertPaths(t, got, "ab.txt", "sub/abcde.txt")
+}
+
+func TestExtOnly(t *testing.T) {
+	root := makeTree(t, "main.go", "notes.gopher", "Makefile", "UTIL.GO", "a.go.bak", "go")
+
+	got := mustFind(t, root, "*.go*")
+	assertPaths(t, got, "a.go.bak", "main.go", "notes.gopher")
+
+	got = mustFind(t, root, "*.go", ExtOnly)
+	assertPaths(t, got, "main.go")
+
+	got = mustFind(t, root, ".go", ExtOnly, Insensitive)
+	assertPaths(t, got, "UTIL.GO", "main.go")
+
+	// Names without extension match only the empty one.
+	got = mustFind(t, root, "!*.*", ExtOnly)
+	assertPaths(t, got, "Makefile", "go")
+}
+
+func TestDefaultOptions(t *testing.T) {
+	def := defaultOptions()
+
+	for _, opts := range []Options{
+		DefaultOptions(),
+		// Defaults reset the options set before them.
+		{Only(File), Max(3), MaxDepth(2), MaxSize(10), WithOptions(DefaultOptions())},
+	} {
+		o := defaultOptionsWithCustom(opts...)
+
+		if o.maxIter != 100 || o.max != -1 || o.fType != Both ||
+			o.maxDepth != -1 || o.minSize != 0 || o.maxSize != -1 ||
+			o.budget != -1 || o.scanLimit != -1 || o.uid != -1 || o.gid != -1 {
+			t.Errorf("unexpected defaults: %+v", o)
+		}
+
+		if o.maxIter != def.maxIter || o.max != def.max || o.fType != def.fType ||
+			o.rec != def.rec || o.name != def.name || o.order != def.order {
+			t.Errorf("defaults differ from the internal ones: %+v", o)
+		}
+
+		ts := NewTemplates([]string{"a", "b"})
+		if o.caseFunc("ABC") != def.caseFunc("ABC") || !o.matchFunc(ts, "a") {
+			t.Error("unexpected default case or match function")
+		}
+	}
+}
+
+func TestWithDepthFilter(t *testing.T) {
+	root := makeTree(t, "config/", "a/config/", "a/b/config/", "a/b/c/config")
+
+	even := WithDepthFilter(func(depth int) bool { return depth%2 == 0 })
+
+	got := mustFind(t, root, "*", Recursively, even)
+	assertPaths(t, got, "a", "a/b/c", "a/b/config", "config")
+
+	// Only the top-level folder.
+	got = mustFind(t, root, "config", Recursively, Only(Folder),
+		WithDepthFilter(func(depth int) bool { return depth == 0 }),
+	)
+	assertPaths(t, got, "config")
+}
+
+func TestWithVisitor_skipDir(t *testing.T) {
+	root := makeTree(t, "a/1.txt", "a/sub/2.txt", "b/3.txt", "b/4.txt", "c/5.txt")
+
+	for _, skip := range []error{ErrSkipDir, fs.SkipDir} {
+		var visited []string
+
+		visit := WithVisitor(func(path string) error {
+			rel, _ := filepath.Rel(root, path)
+			visited = append(visited, filepath.ToSlash(rel))
+
+			// Folder is not traversed, for the file the rest
+			// of its folder is skipped.
+			if rel == "a" || rel == filepath.Join("b", "3.txt") {
+				return skip
+			}
+
+			return nil
+		})
+
+		_, err := Find(context.Background(), root, "*", Recursively, visit)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertPaths(t, visited, "a", "b", "b/3.txt", "c", "c/5.txt")
+	}
+
+	// Skipped folder is still in the results.
+	res, err := Find(context.Background(), root, "*", Recursively, KeepVisited,
+		WithVisitor(func(path string) error {
+			if filepath.Base(path) == "a" {
+				return ErrSkipDir
+			}
+
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "a", "b", "b/3.txt", "b/4.txt", "c", "c/5.txt")
+}
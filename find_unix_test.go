@@ -0,0 +1,52 @@
+//go:build unix
+
+package find
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestOnly_special(t *testing.T) {
+	root := makeTree(t, "1.txt", "sub/")
+
+	if err := syscall.Mkfifo(filepath.Join(root, "sub", "pipe"), 0o644); err != nil {
+		t.Skipf("fifo is not supported: %v", err)
+	}
+
+	got := mustFind(t, root, "*", Recursively, Only(NamedPipe))
+	assertPaths(t, got, "sub/pipe")
+
+	got = mustFind(t, root, "*", Recursively, Only(File))
+	assertPaths(t, got, "1.txt", "sub/pipe")
+
+	got = mustFind(t, root, "*", Recursively, Only(Socket))
+	assertPaths(t, got)
+
+	l, err := net.Listen("unix", filepath.Join(root, "sock"))
+	if err != nil {
+		t.Skipf("unix sockets are not supported: %v", err)
+	}
+	defer l.Close()
+
+	got = mustFind(t, root, "*", Recursively, Only(Socket))
+	assertPaths(t, got, "sock")
+
+	// Character devices are found in /dev on all Unix systems.
+	res, err := Find(context.Background(), "/dev", "null", Only(CharDevice))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, "/dev/null")
+
+	res, err = Find(context.Background(), "/dev", "null", Only(Device))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertPaths(t, res, "/dev/null")
+}
@@ -0,0 +1,192 @@
+package find
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// Name of the file with ignore rules for [RespectGitignore].
+const gitignore = ".gitignore"
+
+// ignoreRule is a single pattern from the .gitignore file.
+type ignoreRule struct {
+	re *regexp.Regexp
+	// Folder with the .gitignore file, pattern is matched against
+	// the path relative to it.
+	base    string
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules are all the rules, which apply to the folder. Rules of
+// the parent folders go first, so the deeper ones can override them.
+type ignoreRules []ignoreRule
+
+// match reports if path is ignored. The last matching rule wins.
+func (rules ignoreRules) match(p string, isDir bool) bool {
+	ignored := false
+
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+
+		// Root of the fs.FS is '.', but its entries have no prefix.
+		rel := p
+		if r.base != "." {
+			rel = strings.TrimPrefix(p, r.base)
+		}
+
+		rel = strings.ReplaceAll(rel, pathSeparator, "/")
+		rel = strings.TrimPrefix(rel, "/")
+
+		if r.re.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+
+	return ignored
+}
+
+// loadIgnore reads .gitignore in the folder and stacks its rules
+// on top of the parent ones.
+func (d *dir) loadIgnore(fsys fileSystem) error {
+	if d.parent != nil {
+		d.ignore = d.parent.ignore
+	}
+
+	f, err := fsys.Open(fsys.Join(d.path, gitignore))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+
+		return err
+	}
+	defer f.Close()
+
+	// Rules of the parent must not be changed.
+	rules := d.ignore[:len(d.ignore):len(d.ignore)]
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if r, ok := parseIgnoreRule(sc.Text()); ok {
+			r.base = d.path
+			rules = append(rules, r)
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return err
+	}
+
+	d.ignore = rules
+
+	return nil
+}
+
+// parseIgnoreRule parses single line of the .gitignore file.
+// Returns false if line is blank or a comment.
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	var r ignoreRule
+
+	line = strings.TrimSuffix(line, "\r")
+
+	// Trailing spaces are ignored unless they are escaped.
+	for strings.HasSuffix(line, " ") && !isEscaped(line, len(line)-1) {
+		line = line[:len(line)-1]
+	}
+
+	if line == "" || strings.HasPrefix(line, "#") {
+		return r, false
+	}
+
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	if line == "" {
+		return r, false
+	}
+
+	// Pattern with the separator at the beginning or in the middle
+	// is relative to the .gitignore location, otherwise it can match
+	// at any level below it.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegexp(line)
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "^(?:.*/)?" + expr + "$"
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return r, false
+	}
+
+	r.re = re
+
+	return r, true
+}
+
+// globToRegexp converts gitignore glob into the regular expression.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		atSegment := i == 0 || glob[i-1] == '/'
+
+		switch {
+		case atSegment && strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case atSegment && glob[i:] == "**":
+			sb.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			sb.WriteString("[^/]*")
+		case glob[i] == '?':
+			sb.WriteString("[^/]")
+		case glob[i] == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == 0 {
+				// Bracket right after the opening one is a part
+				// of the class e.g., '[]a]'.
+				end = strings.IndexByte(glob[i+2:], ']') + 1
+			}
+
+			if end <= 0 {
+				sb.WriteString(`\[`)
+
+				continue
+			}
+
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case glob[i] == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	return sb.String()
+}
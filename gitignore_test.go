@@ -0,0 +1,46 @@
+package find
+
+import "testing"
+
+func TestParseIgnoreRule(t *testing.T) {
+	tests := []struct {
+		rule string
+		path string
+		want bool
+	}{
+		{"*.txt", "a.txt", true},
+		{"*.txt", "dir/a.txt", true},
+		{"/a.txt", "dir/a.txt", false},
+		{"dir/a.txt", "dir/a.txt", true},
+		{"dir/a.txt", "sub/dir/a.txt", false},
+		{"**/dir/a.txt", "sub/dir/a.txt", true},
+		{"dir/**", "dir/sub/a.txt", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"file[0-9].txt", "file1.txt", true},
+		{"file[!0-9].txt", "file1.txt", false},
+		{"file?.txt", "file10.txt", false},
+		{`\#hash`, "#hash", true},
+		{`\!bang`, "!bang", true},
+		{`trailing\ `, "trailing ", true},
+	}
+
+	for _, tt := range tests {
+		r, ok := parseIgnoreRule(tt.rule)
+		if !ok {
+			t.Errorf("parseIgnoreRule(%q) failed", tt.rule)
+
+			continue
+		}
+
+		if got := r.re.MatchString(tt.path); got != tt.want {
+			t.Errorf("%q: match %q = %v, want %v", tt.rule, tt.path, got, tt.want)
+		}
+	}
+
+	for _, line := range []string{"", "   ", "# comment", "!", "/"} {
+		if _, ok := parseIgnoreRule(line); ok {
+			t.Errorf("parseIgnoreRule(%q) should be skipped", line)
+		}
+	}
+}
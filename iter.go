@@ -0,0 +1,151 @@
+//go:build go1.23
+
+package find
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+// Iter acts the same way as [Find] but yields matches one by one,
+// so they can be ranged over:
+//
+//	for path, err := range Iter(ctx, where, ts, opts...) {
+//		if err != nil {
+//			// process error...
+//		}
+//		// do something here...
+//	}
+//
+// Error, if any, is yielded last with an empty path. If [WithErrorsSkip]
+// was set, all skipped errors are joined with it. Breaking out of the loop
+// stops the search and waits for all the workers to finish. Loop body
+// always runs on the calling goroutine, even with [Concurrency].
+//
+// Note: [WithVisitor] has no effect, since Iter uses it internally.
+func Iter[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	opts ...optFunc,
+) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		opt := defaultOptionsWithCustom(opts...)
+		opt.keepErrs = true
+		opt.keepVisit = false
+
+		stopped, err := iterate(ctx, where, t, opt, false, yield)
+		if err = errors.Join(append(opt.skipped, err)...); err != nil && !stopped {
+			yield("", err)
+		}
+	}
+}
+
+// IterWithErrors acts the same way as [Iter], but yields non-critical
+// errors as soon as they occur alongside the path, which caused them,
+// so they interleave with the matches:
+//
+//	for path, err := range IterWithErrors(ctx, where, ts, opts...) {
+//		if err != nil {
+//			// show that path could not be read...
+//			continue
+//		}
+//		// do something here...
+//	}
+//
+// Search continues after such errors. Critical error, if any, is yielded
+// last with an empty path.
+//
+// Note: [WithErrorHandler] and [WithErrorsSkip] have no effect, since
+// IterWithErrors handles all the errors itself.
+func IterWithErrors[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	opts ...optFunc,
+) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		opt := defaultOptionsWithCustom(opts...)
+		opt.keepVisit = false
+
+		stopped, err := iterate(ctx, where, t, opt, true, yield)
+		if err != nil && !stopped {
+			yield("", err)
+		}
+	}
+}
+
+// iterItem is a match or an error passed to the ranging goroutine.
+type iterItem struct {
+	path string
+	err  error
+}
+
+// iterate runs the search in a separate goroutine and yields matches
+// and, if withErrs is set, non-critical errors on the calling one, so
+// the loop body never runs on the workers. Reports if the loop was
+// over before the search and the error of the search.
+func iterate[T Templater](
+	ctx context.Context,
+	where string,
+	t T,
+	opt *options,
+	withErrs bool,
+	yield func(string, error) bool,
+) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	items := make(chan iterItem)
+
+	// send returns [ErrStopWalk] once the loop is over.
+	send := func(item iterItem) error {
+		select {
+		case items <- item:
+			return nil
+		case <-ctx.Done():
+			return ErrStopWalk
+		}
+	}
+
+	opt.visit = func(path string) error {
+		return send(iterItem{path: path})
+	}
+
+	if withErrs {
+		// Handler is called under the lock, so all the workers
+		// can be stopped the same way as visitor does.
+		opt.onError = func(path string, err error) error {
+			if err := send(iterItem{path: path, err: err}); err != nil {
+				opt.max = 0
+
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	var err error
+
+	go func() {
+		defer close(items)
+
+		err = run(ctx, where, t, opt)
+	}()
+
+	stopped := false
+
+	// Items are drained till the end, so workers are finished once
+	// the loop is over.
+	for item := range items {
+		if !stopped && !yield(item.path, item.err) {
+			stopped = true
+
+			cancel()
+		}
+	}
+
+	return stopped, err
+}
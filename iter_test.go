@@ -0,0 +1,184 @@
+//go:build go1.23
+
+package find
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"iter"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIter(t *testing.T) {
+	root := makeTree(t, "a/1.txt", "b/2.txt", "3.txt", "4.log")
+
+	var res []string
+	for path, err := range Iter(context.Background(), root, "*.txt", Recursively) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res = append(res, path)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "3.txt", "a/1.txt", "b/2.txt")
+}
+
+func TestIter_break(t *testing.T) {
+	root := makeWideTree(t, 5, 3)
+	before := runtime.NumGoroutine()
+
+	var res []string
+	for path, err := range Iter(context.Background(), root, "*",
+		Recursively, Concurrency(4),
+	) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res = append(res, path)
+
+		break
+	}
+
+	if len(res) != 1 {
+		t.Fatalf("expected single result, got %v", res)
+	}
+
+	// Workers must be finished as soon as the loop is over.
+	for i := 0; runtime.NumGoroutine() > before; i++ {
+		if i == 100 {
+			t.Fatalf("goroutines leaked: %d > %d", runtime.NumGoroutine(), before)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// goroutineID returns id of the current goroutine from its stack.
+func goroutineID() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	return strings.Fields(string(buf))[1]
+}
+
+func TestIter_goroutine(t *testing.T) {
+	root := makeWideTree(t, 5, 3)
+	id := goroutineID()
+
+	for _, seq := range []iter.Seq2[string, error]{
+		Iter(context.Background(), root, "*", Recursively, Concurrency(4)),
+		IterWithErrors(context.Background(), root, "*",
+			Recursively, Concurrency(4), withUnreadable("d2"),
+		),
+	} {
+		n := 0
+		for range seq {
+			if got := goroutineID(); got != id {
+				t.Fatalf("loop body runs on goroutine %s, want %s", got, id)
+			}
+
+			n++
+		}
+
+		if n == 0 {
+			t.Fatal("expected items")
+		}
+	}
+}
+
+func TestIter_errors(t *testing.T) {
+	root := makeTree(t, "a/", "b/", "c/1.txt")
+
+	var (
+		res  []string
+		errs []error
+	)
+
+	for path, err := range Iter(context.Background(), root, "*.txt",
+		Recursively, WithErrorsSkip, withUnreadable("a", "b"),
+	) {
+		if err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		res = append(res, path)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "c/1.txt")
+
+	if len(errs) != 1 || !errors.Is(errs[0], fs.ErrPermission) {
+		t.Errorf("expected single joined error, got %v", errs)
+	}
+}
+
+func TestIterWithErrors(t *testing.T) {
+	root := makeTree(t, "a/", "b/2.txt", "c/1.txt")
+
+	var (
+		res  []string
+		errs = make(map[string]error)
+	)
+
+	for path, err := range IterWithErrors(context.Background(), root, "*.txt",
+		Recursively, withUnreadable("a"),
+	) {
+		if err != nil {
+			errs[path] = err
+
+			continue
+		}
+
+		res = append(res, path)
+	}
+
+	assertPaths(t, relPaths(t, root, res), "b/2.txt", "c/1.txt")
+
+	err := errs[filepath.Join(root, "a")]
+	if len(errs) != 1 || !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected error of the unreadable folder, got %v", errs)
+	}
+
+	// Breaking on the error stops the search.
+	n := 0
+	for _, err := range IterWithErrors(context.Background(), root, "*.txt",
+		Recursively, withUnreadable("a"),
+	) {
+		n++
+
+		if err != nil {
+			break
+		}
+	}
+
+	if n != 1 {
+		t.Errorf("expected search to stop on the first error, got %d items", n)
+	}
+}
+
+func TestIterWithErrors_concurrency(t *testing.T) {
+	root := makeWideTree(t, 6, 3)
+
+	// Breaking on the error must stop all the workers, so none of them
+	// yields after the loop is over.
+	for i := 0; i < 20; i++ {
+		for _, err := range IterWithErrors(context.Background(), root, "*",
+			Recursively, Concurrency(8), withUnreadable("d5"),
+		) {
+			if err != nil {
+				break
+			}
+
+			// Other workers wait for the lock meanwhile.
+			time.Sleep(100 * time.Microsecond)
+		}
+	}
+}
@@ -1,11 +1,49 @@
 package find
 
 import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/http"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// SortMode defines the order of the results.
+type SortMode uint8
+
+// Sort modes, can be combined with [SortDesc] e.g., SortBySize|SortDesc.
+const (
+	SortByName SortMode = iota + 1
+	SortBySize
+	SortByMTime
+
+	// SortDesc reverses the order.
+	SortDesc SortMode = 1 << 7
+)
+
+// Order defines when folders are matched relative to their content.
+type Order uint8
+
+// Traversal orders.
+const (
+	// PreOrder matches folders before their content.
+	PreOrder Order = iota
+	// PostOrder matches folders after their content e.g., for removal.
+	PostOrder
 )
 
 // Type of the searched object.
@@ -13,14 +51,56 @@ const (
 	File uint8 = iota
 	Folder
 	Both
+	// Symlink matches only symbolic links, whatever they point to.
+	Symlink
+	// NamedPipe matches only named pipes (FIFOs).
+	NamedPipe
+	// Socket matches only Unix domain sockets.
+	Socket
+	// Device matches block and character devices.
+	Device
+	// CharDevice matches only character devices.
+	CharDevice
 )
 
 var sensitive = func(s string) string { return s }
 
+// platformCase returns case func, which matches the file systems
+// of the platform: on Windows they are case insensitive.
+func platformCase(goos string) caseFunc {
+	if goos == "windows" {
+		return strings.ToLower
+	}
+
+	return sensitive
+}
+
+// apply normalizes s with fn. Path separators are kept as is, so
+// borders of the templates are found in s even if fn changes them.
+func (fn caseFunc) apply(s string) string {
+	if fn(separator) == separator {
+		return fn(s)
+	}
+
+	parts := strings.Split(s, separator)
+	for i := range parts {
+		parts[i] = fn(parts[i])
+	}
+
+	return strings.Join(parts, separator)
+}
+
 type (
-	optFunc   func(*options)
-	matchFunc func(Templates, string) bool
-	caseFunc  func(string) string
+	optFunc  func(*options)
+	caseFunc func(string) string
+
+	// FilterFunc reports if entry should be kept in the results,
+	// see [WithFilter].
+	FilterFunc func(path string, d fs.DirEntry) (bool, error)
+
+	// MatchFunc reports if the string matches the templates e.g.,
+	// [MatchAny] or [MatchAll].
+	MatchFunc func(ts Templates, str string) bool
 
 	// Type to create custom slices of find options.
 	Options []optFunc
@@ -28,109 +108,1269 @@ type (
 
 // options allows to configure Find behavior.
 type options struct {
-	matchFunc matchFunc
+	matchFunc MatchFunc
 	caseFunc  caseFunc
+	fsys      fileSystem
+	readDir   dirReader
 	logger    io.Writer
 	output    io.Writer
+	tracer    io.Writer
+	buffered  *bufio.Writer
 	orig      string
 	resOrig   string
 	max       int
 	maxIter   int
+	maxPerDir int
+	maxDepth  int
+	minDepth  int
+	minSize   int64
+	maxSize   int64
+	after     time.Time
+	before    time.Time
+	modeMask  fs.FileMode
+	modeWant  fs.FileMode
+	content   *regexp.Regexp
 	fType     uint8
+	delim     byte
+	exclude   []string
+	excludeTs Templates
+	ts        Templates
+	err       error
+	res       []string
+	infoRes   []Result
+	matchRes  []Match
+	visit     func(string) error
+	walkFn    func(string, fs.DirEntry, bool) error
+	progress  func(ProgressStats)
+	onError   func(string, error) error
+	stats     ProgressStats
+	withStats bool
+	files     int
 	iterCh    chan string
 	errCh     chan error
+	skipped   []error
+	keepErrs  bool
+	workers   int
+	sem       chan struct{}
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	failed    error
+	cancel    context.CancelFunc
 	rec       bool
+	withRoot  bool
 	name      bool
 	relative  bool
 	full      bool
+	relMatch  bool
+	exact     bool
+	glob      bool
+	timeout   time.Duration
+	invert    bool
+	empty     bool
+	linkDedup bool
+	budget    int64
+	types     []string
+	archives  bool
+	uid       int
+	gid       int
+	parentDir bool
+	parents   map[string]struct{}
+	scanLimit int
+	scanned   int
+	expand    bool
+	outTmpl   *template.Template
+	tmplLimit int
+	tmplCount []int
+	tmplDone  bool
+	keepVisit bool
+	filters   []FilterFunc
+	extOnly   bool
+	nested    bool
+	ownMatch  bool
+	foldCase  bool
+	sameFS    bool
+	depthFn   func(depth int) bool
+	hasDev    bool
+	rootDev   uint64
+	inodes    map[inode]struct{}
 	skip      bool
 	log       bool
 	iter      bool
 	out       bool
+	json      bool
+	follow    bool
+	unique    bool
+	broken    bool
+	seen      map[string]struct{}
+	info      bool
+	matches   bool
+	gitignore bool
+	sort      SortMode
+	order     Order
+	noHidden  bool
 }
 
 // defaultOptions default [Find] options.
 func defaultOptions() *options {
 	return &options{
 		matchFunc: MatchAny,
-		caseFunc:  sensitive,
+		caseFunc:  platformCase(runtime.GOOS),
+		fsys:      osFS{},
 		logger:    os.Stdout,
 		output:    os.Stdout,
 		maxIter:   100,
+		delim:     '\n',
+		res:       make([]string, 0),
+		infoRes:   make([]Result, 0),
+		matchRes:  make([]Match, 0),
 		max:       -1,
+		budget:    -1,
+		scanLimit: -1,
+		uid:       -1,
+		gid:       -1,
+		maxDepth:  -1,
+		maxSize:   -1,
 		fType:     Both,
 	}
 }
 
-func defaultOptionsWithCustom(opts ...optFunc) *options {
-	opt := defaultOptions()
+// DefaultOptions returns the preset of the default [Find] options: both
+// files and folders are matched with any of the templates, search is case
+// insensitive only on Windows, without limits of results and depth, and
+// [WithMaxIterator] buffer is 100. Applied after other options, it resets
+// these ones, so it can be used as a base of the presets.
+func DefaultOptions() Options {
+	return Options{
+		Only(Both),
+		WithMatchFunc(MatchAny),
+		WithCaseFunc(platformCase(runtime.GOOS)),
+		WithMaxIterator(100),
+		Max(-1),
+		MaxDepth(-1),
+		MinSize(0),
+		MaxSize(-1),
+		MaxPerDir(0),
+		MaxPerTemplate(0),
+		MaxScanned(-1),
+		WithReadBudget(-1),
+		WithOwner(-1),
+		WithGroup(-1),
+		TraversalOrder(PreOrder),
+	}
+}
+
+func defaultOptionsWithCustom(opts ...optFunc) *options {
+	opt := defaultOptions()
+
+	for _, fn := range opts {
+		fn(opt)
+	}
+
+	return opt
+}
+
+// compile prepares options, which depend on each other, after
+// all of them were applied.
+func (o *options) compile() error {
+	if o.err != nil {
+		return o.err
+	}
+
+	if o.readDir != nil {
+		o.fsys = readerFS{fileSystem: o.fsys, read: o.readDir}
+		o.readDir = nil
+	}
+
+	if len(o.exclude) != 0 {
+		ts, err := compileTemplates(o.exclude, o.caseFunc)
+		if err != nil {
+			return err
+		}
+
+		o.excludeTs = ts
+	}
+
+	return nil
+}
+
+// logError handles error, which occurred with the path p. Returns
+// nil if error should be skipped.
+func (o *options) logError(p string, e error) error {
+	if o.log {
+		o.mu.Lock()
+		_, err := fmt.Fprintf(o.logger, "error: %s\n", e)
+		o.mu.Unlock()
+
+		if err != nil {
+			return fmt.Errorf("%w: %w", e, err)
+		}
+	}
+
+	if o.onError != nil {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+
+		return o.onError(p, e)
+	}
+
+	if o.skip {
+		// Iterators and [WithErrorsCollect] report them at the end.
+		if o.keepErrs {
+			o.mu.Lock()
+			o.skipped = append(o.skipped, e)
+			o.mu.Unlock()
+		}
+
+		return nil
+	}
+
+	// Errors of the root are already classified.
+	if errors.Is(e, ErrRootNotFound) || errors.Is(e, ErrInvalidRoot) {
+		return e
+	}
+
+	return fmt.Errorf("%w: %w", ErrWalk, e)
+}
+
+// collected returns errors collected with [WithErrorsCollect]
+// joined together.
+func (o *options) collected() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return errors.Join(o.skipped...)
+}
+
+// fail saves the first critical error and stops all the workers.
+func (o *options) fail(err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.failed == nil {
+		o.failed = err
+
+		if o.cancel != nil {
+			o.cancel()
+		}
+	}
+}
+
+// isDuplicate reports if entry with the same resolved path
+// was already found.
+func (o *options) isDuplicate(e *entry) (bool, error) {
+	resolved, err := o.fsys.Resolve(e.path)
+	if err != nil {
+		return false, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.seen[resolved]; ok {
+		return true, nil
+	}
+
+	if o.seen == nil {
+		o.seen = make(map[string]struct{})
+	}
+
+	o.seen[resolved] = struct{}{}
+
+	return false, nil
+}
+
+// inode identifies the file on the device.
+type inode struct {
+	dev uint64
+	ino uint64
+}
+
+// isHardlink reports if file with the same inode was already found.
+func (o *options) isHardlink(e *entry) (bool, error) {
+	info, err := e.Info()
+	if err != nil {
+		return false, err
+	}
+
+	id, ok := inodeOf(info)
+	if !ok {
+		return false, nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.inodes[id]; ok {
+		return true, nil
+	}
+
+	if o.inodes == nil {
+		o.inodes = make(map[inode]struct{})
+	}
+
+	o.inodes[id] = struct{}{}
+
+	return false, nil
+}
+
+// withTimeout derives the context with [WithTimeout] deadline,
+// if it was set.
+func (o *options) withTimeout(
+	ctx context.Context,
+) (context.Context, context.CancelFunc) {
+	if o.timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, o.timeout)
+}
+
+// limitReached reports if [Max] or [MaxPerTemplate] results were
+// already found or [WithReadBudget] was spent.
+func (o *options) limitReached() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.max == 0 || o.tmplDone ||
+		(o.content != nil && o.budget == 0)
+}
+
+// takeTemplate returns the first template, which matches the path and
+// has not reached [MaxPerTemplate] limit yet, or nil if there is no such
+// template. Once all the templates reach the limit, search stops.
+func (o *options) takeTemplate(ts Templates, fullPath string) *Template {
+	target := o.searchTarget(fullPath)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.tmplCount == nil {
+		o.tmplCount = make([]int, len(ts))
+	}
+
+	var tmpl *Template
+
+	for i, t := range ts {
+		if o.tmplCount[i] < o.tmplLimit && t.Match(target) {
+			o.tmplCount[i]++
+			tmpl = t
+
+			break
+		}
+	}
+
+	for _, n := range o.tmplCount {
+		if n < o.tmplLimit {
+			return tmpl
+		}
+	}
+
+	// Current match is still emitted, the rest of the search stops.
+	o.tmplDone = true
+
+	return tmpl
+}
+
+// scan counts inspected entry and returns [ErrScanLimit] if there are
+// more of them than [MaxScanned] allows.
+func (o *options) scan() error {
+	if o.scanLimit == -1 {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.scanned++; o.scanned > o.scanLimit {
+		return ErrScanLimit
+	}
+
+	return nil
+}
+
+// spend takes n bytes from [WithReadBudget] and returns how many of
+// them are within it.
+func (o *options) spend(n int) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.budget < 0 {
+		return n
+	}
+
+	if int64(n) > o.budget {
+		n = int(o.budget)
+	}
+
+	o.budget -= int64(n)
+
+	return n
+}
+
+// emit sends found path to the output and saves it in the results.
+// File info is saved only for [FindInfo].
+func (o *options) emit(found string, info fs.FileInfo, tmpl *Template) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Limit could be reached by another worker.
+	if o.max == 0 {
+		return nil
+	}
+
+	if err := o.printOutput(found, info); err != nil {
+		return err
+	}
+
+	// Match is still saved, if visitor skips the folder.
+	var skip error
+
+	if o.visit != nil {
+		if err := o.visit(found); err != nil {
+			switch {
+			case errors.Is(err, ErrSkipDir) || errors.Is(err, fs.SkipDir):
+				skip = fs.SkipDir
+			case errors.Is(err, ErrStopWalk):
+				// Stop all the workers the same way as with the limit.
+				o.max = 0
+
+				return nil
+			default:
+				return err
+			}
+		}
+	}
+
+	switch {
+	case o.visit != nil && !o.keepVisit:
+	case o.iter:
+		o.iterCh <- found
+	case o.matches:
+		o.matchRes = append(o.matchRes, Match{Path: found, Matched: tmpl})
+	case o.info || o.sort != 0:
+		o.infoRes = append(o.infoRes, Result{Path: found, Info: info})
+	default:
+		o.res = append(o.res, found)
+	}
+
+	if o.max != -1 {
+		o.max--
+	}
+
+	o.stats.Matches++
+
+	return skip
+}
+
+// countFiles adds entries of the read folder, which are not folders,
+// to the [FindWithStats] counter.
+func (o *options) countFiles(data []fs.DirEntry) {
+	if !o.withStats {
+		return
+	}
+
+	n := 0
+	for _, f := range data {
+		if !f.IsDir() {
+			n++
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.files += n
+}
+
+// report updates stats with the read folder and passes them
+// to the [WithProgress] callback.
+func (o *options) report(entries int) {
+	if o.progress == nil && !o.withStats {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if entries >= 0 {
+		o.stats.DirsScanned++
+		o.stats.EntriesScanned += entries
+	}
+
+	if o.progress != nil {
+		o.progress(o.stats)
+	}
+}
+
+// visitEntry matches the entry and passes the result to the [Walk]
+// callback. Callback is never called concurrently.
+func (o *options) visitEntry(
+	ts Templates,
+	d *dir,
+	e *entry,
+	isDir bool,
+) (bool, error) {
+	var (
+		matched bool
+		err     error
+	)
+
+	if !e.overLimit {
+		matched, err = o.matchEntry(ts, d, e, isDir)
+	}
+
+	if err != nil {
+		if lErr := o.logError(e.path, err); lErr != nil {
+			return false, lErr
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Search was stopped by another worker.
+	if o.max == 0 {
+		return false, nil
+	}
+
+	err = o.walkFn(e.path, e.DirEntry, matched)
+	if errors.Is(err, fs.SkipAll) {
+		// Stop all the workers the same way as with the limit.
+		o.max = 0
+	}
+
+	return matched, err
+}
+
+// needInfo reports if file info of the found entries is required.
+func (o *options) needInfo() bool {
+	by := o.sort &^ SortDesc
+
+	return o.info || o.json || o.outTmpl != nil ||
+		by == SortBySize || by == SortByMTime
+}
+
+// paths returns found paths in the requested order.
+func (o *options) paths() []string {
+	if o.sort == 0 {
+		return o.res
+	}
+
+	res := make([]string, 0, len(o.infoRes))
+	for _, r := range o.results() {
+		res = append(res, r.Path)
+	}
+
+	return res
+}
+
+// results returns found paths with their file info in the
+// requested order.
+func (o *options) results() []Result {
+	by := o.sort &^ SortDesc
+	if by == 0 {
+		return o.infoRes
+	}
+
+	slices.SortFunc(o.infoRes, func(a, b Result) int {
+		var c int
+
+		switch by {
+		case SortBySize:
+			c = cmp.Compare(a.Info.Size(), b.Info.Size())
+		case SortByMTime:
+			c = a.Info.ModTime().Compare(b.Info.ModTime())
+		}
+
+		// Ties are broken by path to keep the order deterministic.
+		if c == 0 {
+			c = strings.Compare(a.Path, b.Path)
+		}
+
+		if o.sort&SortDesc != 0 {
+			c = -c
+		}
+
+		return c
+	})
+
+	return o.infoRes
+}
+
+func (o *options) printOutput(str string, info fs.FileInfo) error {
+	if !o.out {
+		return nil
+	}
+
+	if o.json {
+		b, err := json.Marshal(jsonResult{
+			Path:  str,
+			Size:  info.Size(),
+			IsDir: info.IsDir(),
+		})
+		if err != nil {
+			return err
+		}
+
+		str = string(b)
+	}
+
+	if o.outTmpl != nil {
+		var sb strings.Builder
+
+		err := o.outTmpl.Execute(&sb, outputLine{
+			Path:    str,
+			Name:    info.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+			IsDir:   info.IsDir(),
+		})
+		if err != nil {
+			return err
+		}
+
+		str = sb.String()
+	}
+
+	_, err := fmt.Fprintf(o.output, "%s%c", str, o.delim)
+
+	return err
+}
+
+// flush writes buffered output of [WithBufferedWriter].
+func (o *options) flush() error {
+	if o.buffered == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.buffered.Flush()
+}
+
+// outputLine is data of the line printed with [WithOutputTemplate].
+type outputLine struct {
+	Path    string
+	Name    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// jsonResult is a line printed with [WithJSONOutput].
+type jsonResult struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	IsDir bool   `json:"is_dir"`
+}
+
+// prune returns the reason why entry of the folder should be neither
+// matched nor traversed or empty string if it should not be pruned.
+func (o *options) prune(d *dir, p string, f os.DirEntry) string {
+	switch {
+	case o.noHidden && strings.HasPrefix(f.Name(), "."):
+		return "hidden"
+	case len(o.excludeTs) != 0 && MatchAny(o.excludeTs, o.target(p)):
+		return "excluded"
+	case d.ignore.match(p, f.IsDir()):
+		return "gitignore"
+	default:
+		return ""
+	}
+}
+
+// trace writes the action with the path and its reason, if any,
+// to the [WithTrace] writer. Write errors are ignored, since trace
+// is only for debugging.
+func (o *options) trace(action, p, reason string) {
+	if o.tracer == nil {
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if reason == "" {
+		fmt.Fprintf(o.tracer, "%s: %s\n", action, p)
+
+		return
+	}
+
+	fmt.Fprintf(o.tracer, "%s: %s (%s)\n", action, p, reason)
+}
+
+// isDir reports if entry is a folder. Symlinks are resolved
+// only if [FollowSymlinks] was set, broken ones count as files.
+func (o *options) isDir(e *entry) (bool, error) {
+	if !o.follow || e.Type()&os.ModeSymlink == 0 {
+		return e.IsDir(), nil
+	}
+
+	info, err := o.fsys.Stat(e.path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	// Filters should check the target, not the symlink itself.
+	e.info = info
+
+	return info.IsDir(), nil
+}
+
+// matchEntry reports if entry at the given folder should be in
+// the results.
+func (o *options) matchEntry(
+	ts Templates,
+	d *dir,
+	e *entry,
+	isDir bool,
+) (bool, error) {
+	if !o.atDepth(d.depth) ||
+		!o.isSearchedType(e, isDir) ||
+		!o.match(ts, e.path) {
+		return false, nil
+	}
+
+	if ok, err := o.userFilter(e); !ok || err != nil {
+		return false, err
+	}
+
+	if o.broken {
+		if ok, err := o.isBroken(e); !ok || err != nil {
+			return false, err
+		}
+	}
+
+	if o.empty {
+		if ok, err := o.isEmpty(e, isDir); !ok || err != nil {
+			return false, err
+		}
+	}
+
+	// Folders have no content to match.
+	if isDir && (o.content != nil || o.types != nil) {
+		return false, nil
+	}
+
+	ok, err := o.filter(e, isDir)
+	if !ok || err != nil {
+		return ok, err
+	}
+
+	if o.types != nil {
+		if ok, err := o.matchType(e); !ok || err != nil {
+			return false, err
+		}
+	}
+
+	if o.content == nil {
+		return true, nil
+	}
+
+	return o.matchContent(e)
+}
+
+// matchType reports if sniffed content type of the regular file starts
+// with any of [WithContentType] types.
+func (o *options) matchType(e *entry) (bool, error) {
+	info, err := e.Info()
+	if err != nil {
+		return false, err
+	}
+
+	if !info.Mode().IsRegular() {
+		return false, nil
+	}
+
+	f, err := o.fsys.Open(e.path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	// Only the first 512 bytes are considered by the detection.
+	buf := make([]byte, 512)
+
+	n, err := io.ReadFull(f, buf)
+	if err != nil &&
+		!errors.Is(err, io.EOF) &&
+		!errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, err
+	}
+
+	ct := http.DetectContentType(buf[:n])
+
+	for _, t := range o.types {
+		if strings.HasPrefix(ct, t) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// isBroken reports if entry is a symlink to the missing target.
+func (o *options) isBroken(e *entry) (bool, error) {
+	if e.Type()&fs.ModeSymlink == 0 {
+		return false, nil
+	}
+
+	_, err := o.fsys.Stat(e.path)
+	if err == nil {
+		return false, nil
+	}
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+
+	return false, err
+}
+
+// userFilter reports if entry passes all the [WithFilter] functions.
+func (o *options) userFilter(e *entry) (bool, error) {
+	for _, fn := range o.filters {
+		if ok, err := fn(e.path, e); !ok || err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// isEmpty reports if entry is a folder without entries.
+func (o *options) isEmpty(e *entry, isDir bool) (bool, error) {
+	if !isDir {
+		return false, nil
+	}
+
+	entries, err := o.fsys.ReadDir(e.path)
+	if err != nil {
+		return false, err
+	}
+
+	return len(entries) == 0, nil
+}
+
+// matchContent reports if content of the regular file matches
+// [WithContent] expression. File is read only until the first match.
+func (o *options) matchContent(e *entry) (bool, error) {
+	info, err := e.Info()
+	if err != nil {
+		return false, err
+	}
+
+	// Reading from pipes or devices may never end.
+	if !info.Mode().IsRegular() {
+		return false, nil
+	}
+
+	f, err := o.fsys.Open(e.path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	r := &runeReader{r: bufio.NewReader(budgetReader{f, o})}
+	if o.content.MatchReader(r) {
+		return true, nil
+	}
+
+	return false, r.err
+}
+
+// budgetReader ends the input as soon as [WithReadBudget] is spent.
+type budgetReader struct {
+	r io.Reader
+	o *options
+}
+
+func (br budgetReader) Read(p []byte) (int, error) {
+	n, err := br.r.Read(p)
+	if k := br.o.spend(n); k < n {
+		return k, io.EOF
+	}
+
+	return n, err
+}
+
+// runeReader saves read error, which is treated as the end
+// of the input by [regexp.Regexp.MatchReader].
+type runeReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (rr *runeReader) ReadRune() (rune, int, error) {
+	r, size, err := rr.r.ReadRune()
+	if err != nil && !errors.Is(err, io.EOF) {
+		rr.err = err
+	}
+
+	return r, size, err
+}
+
+// filter reports if entry passes filters, which require file info.
+func (o *options) filter(e *entry, isDir bool) (bool, error) {
+	size := !isDir && (o.minSize != 0 || o.maxSize != -1)
+	modTime := !o.after.IsZero() || !o.before.IsZero()
+	mode := o.modeMask != 0
+	owner := o.uid != -1 || o.gid != -1
+
+	if !size && !modTime && !mode && !owner {
+		return true, nil
+	}
+
+	info, err := e.Info()
+	if err != nil {
+		return false, err
+	}
+
+	if size && (info.Size() < o.minSize ||
+		o.maxSize != -1 && info.Size() > o.maxSize) {
+		return false, nil
+	}
+
+	if modTime && (!o.after.IsZero() && !info.ModTime().After(o.after) ||
+		!o.before.IsZero() && !info.ModTime().Before(o.before)) {
+		return false, nil
+	}
+
+	if mode && info.Mode()&o.modeMask != o.modeWant {
+		return false, nil
+	}
+
+	// Entries without owner info are not filtered.
+	if uid, gid, ok := ownerOf(info); owner && ok &&
+		(o.uid != -1 && uid != o.uid || o.gid != -1 && gid != o.gid) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// emitEntry emits the found entry in the requested form.
+func (o *options) emitEntry(ts Templates, e *entry) error {
+	p, name, info := e.path, e.Name(), e.info
+
+	if o.parentDir {
+		p = o.fsys.Dir(e.path)
+		if !o.firstParent(p) {
+			return nil
+		}
+
+		name = path.Base(toSlash(p))
+		info = nil
+
+		if o.needInfo() {
+			var err error
+			if info, err = o.fsys.Stat(p); err != nil {
+				return o.logError(p, err)
+			}
+		}
+	}
+
+	var found string
+
+	switch {
+	case o.name:
+		found = name
+	case o.relative:
+		found = o.relPath(p)
+	default:
+		found = p
+	}
+
+	tmpl := e.tmpl
+	if o.matches && tmpl == nil {
+		tmpl = o.matched(ts, e.path)
+	}
+
+	return o.emit(found, info, tmpl)
+}
+
+// firstParent reports if parent folder was not found yet
+// for [WithParentDirs].
+func (o *options) firstParent(p string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.parents[p]; ok {
+		return false
+	}
+
+	if o.parents == nil {
+		o.parents = make(map[string]struct{})
+	}
+
+	o.parents[p] = struct{}{}
+
+	return true
+}
+
+// relPath replaces resolved root in the found path with the
+// original one given by the caller.
+func (o *options) relPath(p string) string {
+	if o.orig == o.resOrig {
+		return p
+	}
+
+	rel, err := filepath.Rel(o.resOrig, p)
+	if err != nil {
+		return p
+	}
+
+	if rel == "." {
+		return o.orig
+	}
+
+	return strings.TrimSuffix(o.orig, pathSeparator) + pathSeparator + rel
+}
+
+func (o *options) isSearchedType(e *entry, isDir bool) bool {
+	switch {
+	case o.fType == Symlink:
+		return e.Type()&fs.ModeSymlink != 0
+	case o.fType == NamedPipe:
+		return e.Type()&fs.ModeNamedPipe != 0
+	case o.fType == Socket:
+		return e.Type()&fs.ModeSocket != 0
+	case o.fType == Device:
+		return e.Type()&fs.ModeDevice != 0
+	case o.fType == CharDevice:
+		return e.Type()&fs.ModeCharDevice != 0
+	case o.fType == Folder:
+		return isDir
+	case o.fType == File:
+		return !isDir
+	default:
+		return true
+	}
+}
+
+// onRootDevice reports if folder is on the same device as the root
+// for [SameFilesystem]. Folders without device info are traversed.
+func (o *options) onRootDevice(e *entry) bool {
+	if !o.sameFS || !o.hasDev {
+		return true
+	}
+
+	info, err := e.Info()
+	if err != nil {
+		return true
+	}
+
+	dev, ok := deviceOf(info)
+
+	return !ok || dev == o.rootDev
+}
+
+// atDepth reports if entries at the given depth can be matched
+// with [MinDepth] and [WithDepthFilter].
+func (o *options) atDepth(depth int) bool {
+	return depth >= o.minDepth && (o.depthFn == nil || o.depthFn(depth))
+}
 
-	for _, fn := range opts {
-		fn(opt)
+// canDescend reports if search can go deeper than the given depth.
+func (o *options) canDescend(depth int) bool {
+	return o.maxDepth == -1 || depth < o.maxDepth
+}
+
+func (o *options) match(ts Templates, fullPath string) bool {
+	return o.matchFunc(ts, o.searchTarget(fullPath)) != o.invert
+}
+
+// matched returns the first template, which matches the path.
+// Inverted match has no template.
+func (o *options) matched(ts Templates, fullPath string) *Template {
+	if o.invert {
+		return nil
 	}
 
-	return opt
+	if i := MatchIndex(ts, o.searchTarget(fullPath)); i != -1 {
+		return ts[i]
+	}
+
+	return nil
 }
 
-func (o *options) logError(e error) error {
-	if o.log {
-		if _, err := fmt.Fprintf(o.logger, "error: %s\n", e); err != nil {
-			return fmt.Errorf("%w: %w", e, err)
-		}
+// searchTarget acts the same way as target, but returns only the name
+// if [ExactName] was set or its extension with [ExtOnly].
+func (o *options) searchTarget(fullPath string) string {
+	if o.extOnly {
+		return o.caseFunc(path.Ext(path.Base(toSlash(fullPath))))
 	}
 
-	if o.skip {
-		return nil
+	if o.exact {
+		return o.caseFunc(path.Base(toSlash(fullPath)))
 	}
 
-	return e
+	if o.nested && !o.full {
+		return o.nestedTarget(fullPath)
+	}
+
+	return o.target(fullPath)
 }
 
-func (o *options) printOutput(str string) error {
-	if o.out {
-		if _, err := fmt.Fprintln(o.output, str); err != nil {
-			return err
-		}
+// nestedTarget returns the path relative to the root for [NestedAnd],
+// so chains of the templates are matched against the folders below it.
+func (o *options) nestedTarget(fullPath string) string {
+	p := fullPath
+	if rel, err := filepath.Rel(o.resOrig, fullPath); err == nil {
+		p = rel
 	}
 
-	return nil
+	return o.caseFunc.apply(toSlash(p))
 }
 
-func (o *options) isSearchedType(isDir bool) bool {
-	switch {
-	case o.fType == Folder:
-		return isDir
-	case o.fType == File:
-		return !isDir
-	default:
+// canNest reports if any of the templates can match below the folder
+// at the given depth with [NestedAnd]. Each template of the '&' chain
+// needs its own folder, so folders too deep for [MaxDepth] are skipped.
+// Custom [WithMatchFunc], [WithInvert] and [Walk] need all the entries.
+func (o *options) canNest(ts Templates, p string, depth int) bool {
+	if !o.nested || o.maxDepth == -1 || o.ownMatch ||
+		o.invert || o.walkFn != nil {
 		return true
 	}
+
+	parts := strings.Split(o.nestedTarget(p), separator)
+	levels := o.maxDepth - depth + 1
+
+	for _, t := range ts {
+		if t.remaining(parts) <= levels {
+			return true
+		}
+	}
+
+	return false
 }
 
-func (o *options) match(ts Templates, fullPath string) bool {
-	if o.full {
-		return o.matchFunc(ts, o.caseFunc(fullPath))
+// target returns the part of the path, which should be matched
+// with templates.
+func (o *options) target(fullPath string) string {
+	p := fullPath
+
+	if o.relMatch {
+		if rel, err := filepath.Rel(o.resOrig, fullPath); err == nil {
+			p = rel
+		}
+	}
+
+	p = toSlash(p)
+
+	if !o.full {
+		p = path.Base(p)
 	}
 
-	return o.matchFunc(ts, o.caseFunc(path.Base(fullPath)))
+	return o.caseFunc.apply(p)
 }
 
 // Deprecated: use [Only] instead.
 func SearchFor(t uint8) optFunc { return Only(t) }
 
-// Only defines if result should contains files, folders or both.
+// Only defines if result should contains files, folders, both, symlinks,
+// named pipes, sockets or devices. Special files count as files too.
 func Only(t uint8) optFunc {
 	return func(o *options) {
 		o.fType = t
 	}
 }
 
+// IncludeRoot matches the root folder itself as well as its entries.
+// Root is treated as an entry of the depth 0.
+func IncludeRoot(o *options) { o.withRoot = true }
+
 // Deprecated: use [Recursively] instead.
 func SearchRecursively(o *options) { Recursively(o) }
 
 // Recursively defines recursive search.
 func Recursively(o *options) { o.rec = true }
 
+// MaxDepth limits recursive search with the given depth below the root.
+// Depth 0 means only the root entries, 1 - the root entries and entries
+// of its subfolders, etc. Negative value removes the limit.
+//
+// Note: has effect only with [Recursively].
+func MaxDepth(n int) optFunc {
+	return func(o *options) {
+		if n < 0 {
+			n = -1
+		}
+
+		o.maxDepth = n
+	}
+}
+
+// MinDepth skips matches, which are located less than the given depth
+// below the root. Such folders are still traversed, but not returned
+// and do not count towards [Max].
+//
+// Note: has effect only with [Recursively].
+func MinDepth(n int) optFunc {
+	return func(o *options) {
+		o.minDepth = n
+	}
+}
+
+// WithDepthFilter skips matches, for which depth below the root fn
+// returns false. Depth 0 means the root entries. As with [MinDepth],
+// folders are still traversed.
+//
+// Note: fn can be called concurrently with [Concurrency].
+func WithDepthFilter(fn func(depth int) bool) optFunc {
+	return func(o *options) {
+		o.depthFn = fn
+	}
+}
+
+// FollowSymlinks descends into folders reachable through symlinks
+// during recursive search. Found paths keep the symlink in them.
+// Symlinks to folders also count as folders for [Only].
+//
+// Folders, which were already visited on the way from the root, are not
+// entered again and reported as [ErrSymlinkLoop] to break infinite
+// recursion. Such error can be skipped with [WithErrorsSkip]. Only loops
+// are broken: folder reachable through several symlinks is traversed
+// once for each of them, use [Unique] to get its entries only once.
+func FollowSymlinks(o *options) { o.follow = true }
+
+// SkipHidden ignores files and folders, which names start with '.'.
+// Hidden folders are not traversed. The root is never skipped.
+func SkipHidden(o *options) { o.noHidden = true }
+
+// Exclude ignores files and folders, which match any of the given
+// templates. Excluded folders are not traversed. Respects [MatchFullPath]
+// and [Insensitive] the same way as search templates do.
+func Exclude[T Templater](t T) optFunc {
+	return func(o *options) {
+		sl, err := templateStrings(t)
+		if err != nil {
+			o.err = err
+
+			return
+		}
+
+		o.exclude = append(o.exclude, sl...)
+	}
+}
+
+// RespectGitignore ignores files and folders, which match rules of
+// the .gitignore files met during the search. Rules of the nested
+// .gitignore files are applied on top of the parent ones.
+//
+// Note: '.git' folder is not ignored by default, use [Exclude]
+// or [SkipHidden] for it.
+func RespectGitignore(o *options) { o.gitignore = true }
+
+// Unique skips matches, which resolve to the already found path. It
+// is useful with [FollowSymlinks] or overlapping roots of [FindMany].
+func Unique(o *options) { o.unique = true }
+
 // Deprecated: use [Name] instead.
 func SearchName(o *options) { Name(o) }
 
@@ -144,9 +1384,90 @@ func SearchStrict(o *options) { Strict(o) }
 // Strict requires all templates to match searched path.
 func Strict(o *options) { o.matchFunc = MatchAll }
 
+// WithMatchFunc sets the function, which combines results of the
+// templates e.g., to require at least two of them to match. Defaults
+// to [MatchAny], [Strict] sets [MatchAll]. Nil fn sets [MatchAny].
+func WithMatchFunc(fn MatchFunc) optFunc {
+	return func(o *options) {
+		if fn == nil {
+			o.matchFunc = MatchAny
+			o.ownMatch = false
+
+			return
+		}
+
+		o.matchFunc = fn
+		o.ownMatch = true
+	}
+}
+
+// WithHardlinkDedup returns hardlinked file only once, the first found
+// link wins, so with [Concurrency] it is not defined which one.
+//
+// Note: has effect only on Unix, other platforms do not provide inode
+// numbers.
+func WithHardlinkDedup(o *options) { o.linkDedup = true }
+
+// OnlyEmpty returns only folders without entries. Each matched folder
+// is read one more time, errors are handled as any other ones e.g., with
+// [WithErrorsSkip].
+func OnlyEmpty(o *options) { o.empty = true }
+
+// WithInvert inverts combined result of the templates, so only entries,
+// which do not match them, are in the results. With [Strict] these are
+// entries, which fail at least one of the templates. Other filters e.g.,
+// [MinSize] are not inverted.
+func WithInvert(o *options) { o.invert = true }
+
 // MatchFullPath matches full path not just the name.
 func MatchFullPath(o *options) { o.full = true }
 
+// NestedAnd matches '&' chains of the templates against the nested
+// folders e.g., '*project*&main.go' finds 'main.go' only inside folders,
+// which match '*project*' at any depth below the root. Chains inside
+// groups and '|' are nested the same way, templates without chain are
+// matched as usual e.g., with [MatchFullPath]. Without it all the parts
+// of the chain must match the same name. With [MaxDepth] folders, which
+// are too deep for the rest of the chain, are not traversed.
+func NestedAnd(o *options) { o.nested = true }
+
+// ExactName matches names, which are equal to one of the templates.
+// Wildcards and other special characters are matched literally, full
+// path is never matched even with [MatchFullPath].
+func ExactName(o *options) { o.exact = true }
+
+// SameFilesystem does not descend into folders on other devices than
+// the root e.g., mount points of network file systems. Such folders are
+// still matched themselves.
+//
+// Note: has effect only on Unix, other platforms do not provide device
+// ids.
+func SameFilesystem(o *options) { o.sameFS = true }
+
+// ExtOnly matches templates with the extension of the name including
+// the dot e.g., '.go' for 'main.go', so '*.go' does not match
+// 'notes.gopher'. Names without extension are matched as an empty
+// string. Combine with [Insensitive] to ignore case of the extension.
+func ExtOnly(o *options) { o.extOnly = true }
+
+// GlobMode parses templates as glob patterns of [path.Match] instead
+// of the package syntax e.g., 'file[0-9].txt'. Malformed pattern is
+// returned as an error by [Find].
+func GlobMode(o *options) { o.glob = true }
+
+// MatchRelativePath matches path relative to the root not just the
+// name, so templates do not depend on the root location.
+func MatchRelativePath(o *options) {
+	o.full = true
+	o.relMatch = true
+}
+
+// ExpandPath replaces leading '~' in the searched path with the home
+// folder of the user and $VAR or ${VAR} with the values of environment
+// variables before it is resolved. Undefined variables are replaced
+// with an empty string.
+func ExpandPath(o *options) { o.expand = true }
+
 // RelativePaths does not resolve paths in the output.
 //
 // Note: does not work with [Name] option.
@@ -155,9 +1476,18 @@ func RelativePaths(o *options) { o.relative = true }
 // WithErrorsSkip skips errors during find execution.
 //
 // Note: if the flag was set, [Find] will return nil error,
-// only if the base path was resolved.
+// only if the base path was resolved. [FindWithIterator] still
+// reports skipped errors once the search is over.
 func WithErrorsSkip(o *options) { o.skip = true }
 
+// WithErrorsCollect skips errors the same way as [WithErrorsSkip], but
+// returns them joined with [errors.Join] once the search is over alongside
+// all the found results.
+func WithErrorsCollect(o *options) {
+	o.skip = true
+	o.keepErrs = true
+}
+
 // WithErrorsLog logs errors during find execution.
 // Defaults to [os.Stdout] and can be changed with [WithLogger].
 func WithErrorsLog(o *options) { o.log = true }
@@ -166,7 +1496,50 @@ func WithErrorsLog(o *options) { o.log = true }
 // Defaults to [os.Stdout] and can be changed with [WithWriter].
 func WithOutput(o *options) { o.out = true }
 
-// WithWriter allows to set custom [io.Writer] for [WithPrint].
+// WithNullDelimiter separates printed results with the null character
+// instead of the new line, e.g. for `xargs -0`. Does not affect the
+// returned results.
+func WithNullDelimiter(o *options) { o.delim = 0 }
+
+// WithBufferedWriter acts the same way as [WithWriter], but buffers
+// output with the given size to write it in batches. Buffer is flushed
+// when the search is over, even if it failed or was canceled.
+func WithBufferedWriter(w io.Writer, size int) optFunc {
+	return func(o *options) {
+		o.buffered = bufio.NewWriterSize(w, size)
+		o.output = o.buffered
+		o.out = true
+	}
+}
+
+// WithJSONOutput prints each result as a JSON object with its path,
+// size and type e.g., {"path":"a.txt","size":3,"is_dir":false}.
+// Also sets [WithOutput] to true.
+func WithJSONOutput(o *options) {
+	o.json = true
+	o.out = true
+}
+
+// WithOutputTemplate prints each result formatted with [text/template]
+// e.g., "{{.Size}}\t{{.Path}}". Available fields are .Path, .Name, .Size,
+// .ModTime and .IsDir. Delimiter is added after each line. If format
+// cannot be parsed, [Find] returns the error. Also sets [WithOutput]
+// to true.
+func WithOutputTemplate(format string) optFunc {
+	return func(o *options) {
+		t, err := template.New("output").Parse(format)
+		if err != nil {
+			o.err = err
+
+			return
+		}
+
+		o.outTmpl = t
+		o.out = true
+	}
+}
+
+// WithWriter allows to set custom [io.Writer] for [WithOutput].
 // Also sets [WithOutput] to true.
 //
 // Note: write error counts as critical and will be returned
@@ -200,16 +1573,343 @@ func WithMaxIterator(max int) optFunc {
 }
 
 // Max set maximum ammount of searched objects. [Find] will stop as
-// soon as reach the limitation.
+// soon as reach the limitation. Limit is shared by all the folders and
+// workers, so it caps the total number of results.
 func Max(i int) optFunc {
 	return func(o *options) {
 		o.max = i
 	}
 }
 
-// Insensitive sets case insensitive search.
+// BrokenSymlinks matches only symlinks, which targets do not exist.
+func BrokenSymlinks(o *options) { o.broken = true }
+
+// TraversalOrder defines if folders are matched before or after their
+// content. Defaults to [PreOrder].
+//
+// Note: with [PostOrder] subfolders are traversed by the same worker,
+// so [Concurrency] has no effect.
+func TraversalOrder(order Order) optFunc {
+	return func(o *options) {
+		o.order = order
+	}
+}
+
+// WithTimeout stops the search with [context.DeadlineExceeded] after
+// the given duration. Parent context can still cancel it earlier. Zero
+// or negative value removes the limit.
+func WithTimeout(d time.Duration) optFunc {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// MaxPerTemplate limits number of matches of each template. Path is
+// counted for the first template, which matches it and has not reached
+// the limit yet. Search stops, once all the templates reach it. Zero or
+// negative value removes the limit.
+//
+// Note: has no effect with [WithInvert].
+func MaxPerTemplate(n int) optFunc {
+	return func(o *options) {
+		o.tmplLimit = n
+	}
+}
+
+// MaxScanned stops the search with [ErrScanLimit], if it inspected more
+// than n entries, even if they did not match. Unlike [Max] it limits the
+// work done over the tree, results found so far are returned alongside
+// the error. Negative value removes the limit.
+func MaxScanned(n int) optFunc {
+	return func(o *options) {
+		if n < 0 {
+			n = -1
+		}
+
+		o.scanLimit = n
+	}
+}
+
+// MaxPerDir limits number of matches in each folder, the rest of its
+// entries are not matched, but subfolders are still traversed. Zero or
+// negative value removes the limit.
+func MaxPerDir(n int) optFunc {
+	return func(o *options) {
+		o.maxPerDir = n
+	}
+}
+
+// MinSize skips files, which are smaller than the given size in bytes.
+// Folders are not affected.
+func MinSize(bytes int64) optFunc {
+	return func(o *options) {
+		o.minSize = bytes
+	}
+}
+
+// MaxSize skips files, which are larger than the given size in bytes.
+// Folders are not affected.
+func MaxSize(bytes int64) optFunc {
+	return func(o *options) {
+		o.maxSize = bytes
+	}
+}
+
+// ModifiedAfter skips files and folders, which were modified
+// before or at the given time.
+func ModifiedAfter(t time.Time) optFunc {
+	return func(o *options) {
+		o.after = t
+	}
+}
+
+// ModifiedBefore skips files and folders, which were modified
+// after or at the given time. Can be combined with [ModifiedAfter]
+// to define time range.
+func ModifiedBefore(t time.Time) optFunc {
+	return func(o *options) {
+		o.before = t
+	}
+}
+
+// Concurrency allows to traverse up to n folders in parallel during
+// recursive search. Values less than 2 mean sequential search.
+//
+// Note: order of the results is not defined in this case.
+func Concurrency(n int) optFunc {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// Sort sorts the results in the given order after the search is over.
+// Paths are compared as strings with [SortByName] and in case of equal
+// size or modification time. [SortDesc] alone sorts by name.
+//
+// Note: has no effect with [FindWithIterator] and [WithOutput], since
+// they produce results during the search.
+func Sort(mode SortMode) optFunc {
+	return func(o *options) {
+		if mode == SortDesc {
+			mode |= SortByName
+		}
+
+		o.sort = mode
+	}
+}
+
+// WithContent skips files, which content does not match re. Folders
+// and files, which are not regular e.g., pipes, never match. Files are
+// streamed, so only the part up to the first match is read.
+func WithContent(re *regexp.Regexp) optFunc {
+	return func(o *options) {
+		o.content = re
+	}
+}
+
+// WithParentDirs returns parent folders of the matches instead of the
+// matches themselves. Each folder is returned only once, [Max] limits
+// the number of folders.
+func WithParentDirs(o *options) { o.parentDir = true }
+
+// WithOwner skips entries, which are not owned by the user with the
+// given id. Negative value removes the filter.
+//
+// Note: has effect only on Unix, other platforms do not provide owner
+// ids, so entries are not filtered.
+func WithOwner(uid int) optFunc {
+	return func(o *options) {
+		if uid < 0 {
+			uid = -1
+		}
+
+		o.uid = uid
+	}
+}
+
+// WithGroup skips entries, which are not owned by the group with the
+// given id. Negative value removes the filter.
+//
+// Note: has effect only on Unix, other platforms do not provide owner
+// ids, so entries are not filtered.
+func WithGroup(gid int) optFunc {
+	return func(o *options) {
+		if gid < 0 {
+			gid = -1
+		}
+
+		o.gid = gid
+	}
+}
+
+// WithArchives searches in zip archives as in folders: their entries are
+// matched with templates and found as 'archive.zip/inner/path'. Archive
+// itself is matched as any other file. Filters, which read the files e.g.,
+// [WithContent], never match archived entries.
+//
+// Note: has no effect with [Walk].
+func WithArchives(o *options) { o.archives = true }
+
+// WithContentType skips files, which content type does not start with
+// any of the given types e.g., "image/" or "text/plain". Type is detected
+// by [http.DetectContentType] from the first 512 bytes of the file,
+// folders are skipped as well.
+func WithContentType(types ...string) optFunc {
+	return func(o *options) {
+		o.types = append(o.types[:0:0], types...)
+	}
+}
+
+// WithReadBudget limits total amount of bytes read by [WithContent].
+// As soon as budget is spent, search stops and returns matches found so
+// far. Negative value removes the limit.
+func WithReadBudget(bytes int64) optFunc {
+	return func(o *options) {
+		if bytes < 0 {
+			bytes = -1
+		}
+
+		o.budget = bytes
+	}
+}
+
+// ProgressStats are counters of the search passed to [WithProgress].
+type ProgressStats struct {
+	// Number of folders read.
+	DirsScanned int
+	// Number of entries in the read folders.
+	EntriesScanned int
+	// Number of found matches.
+	Matches int
+}
+
+// WithProgress calls fn with the current stats after each folder is read
+// and once the search is over.
+//
+// Note: fn is never called concurrently, even with [Concurrency], but it
+// blocks the search, so it should return quickly.
+func WithProgress(fn func(stats ProgressStats)) optFunc {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// WithErrorHandler calls fn for each error during the search instead of
+// [WithErrorsSkip]. If fn returns nil, search continues, [ErrSkipDir] skips
+// the rest of the folder, where error occurred, or the folder itself if it
+// cannot be read. Any other error stops the search and is returned.
+//
+// Note: fn is never called concurrently, even with [Concurrency].
+func WithErrorHandler(fn func(path string, err error) error) optFunc {
+	return func(o *options) {
+		o.onError = fn
+	}
+}
+
+// WithTrace writes each folder entered during the search and each
+// pruned entry with the reason e.g., "excluded" or "max depth" to w.
+// It is separate from [WithErrorsLog] and meant for debugging.
+func WithTrace(w io.Writer) optFunc {
+	return func(o *options) {
+		o.tracer = w
+	}
+}
+
+// withDirReader reads folders with fn instead of [os.ReadDir], so
+// the traversal can be tested without the real files.
+func withDirReader(fn dirReader) optFunc {
+	return func(o *options) {
+		o.readDir = fn
+	}
+}
+
+// WithVisitor calls fn for each match instead of saving it in the
+// results. If fn returns an error, search stops and [Find] returns it.
+// Return [ErrStopWalk] to stop the search without an error. Return
+// [ErrSkipDir] or [fs.SkipDir] for the folder to not traverse it, for
+// the file - to skip the rest of its folder. Use [KeepVisited] to save
+// the results as well.
+//
+// Note: fn is never called concurrently, even with [Concurrency].
+func WithVisitor(fn func(path string) error) optFunc {
+	return func(o *options) {
+		o.visit = fn
+	}
+}
+
+// WithFilter skips entries, for which fn returns false. It is called
+// only for entries, which passed type and template checks. Error is
+// handled as any other one e.g., with [WithErrorsSkip]. Several filters
+// can be set, entry is kept only if all of them return true.
+//
+// Note: fn can be called concurrently with [Concurrency].
+func WithFilter(fn FilterFunc) optFunc {
+	return func(o *options) {
+		o.filters = append(o.filters[:len(o.filters):len(o.filters)], fn)
+	}
+}
+
+// KeepVisited saves the results passed to [WithVisitor] callback, so
+// [Find] returns them as usual. Match, for which callback returned an
+// error, is not saved.
+func KeepVisited(o *options) { o.keepVisit = true }
+
+// WithMode skips files and folders, which mode bits selected by mask
+// are not equal to want e.g., WithMode(0o001, 0o001) finds entries
+// executable by anyone and WithMode(fs.ModeSetuid, fs.ModeSetuid)
+// finds entries with setuid bit.
+func WithMode(mask, want fs.FileMode) optFunc {
+	return func(o *options) {
+		o.modeMask = mask
+		o.modeWant = want
+	}
+}
+
+// Insensitive sets case insensitive search. Both templates and matched
+// names are lowercased, found paths keep their case as is.
+// It is the default on Windows.
 func Insensitive(o *options) {
 	o.caseFunc = strings.ToLower
+	o.foldCase = true
+}
+
+// Sensitive sets case sensitive search. It is the default on all
+// platforms except Windows.
+func Sensitive(o *options) {
+	o.caseFunc = sensitive
+	o.foldCase = false
+}
+
+// WithCaseFunc sets custom normalization of the templates and matched
+// names instead of [Insensitive] e.g., to fold Unicode or strip accents.
+// fn must keep special characters of the templates as is, path
+// separators are kept even if fn changes them. Nil fn sets case
+// sensitive search.
+func WithCaseFunc(fn func(string) string) optFunc {
+	return func(o *options) {
+		if fn == nil {
+			Sensitive(o)
+
+			return
+		}
+
+		o.caseFunc = fn
+		o.foldCase = true
+	}
+}
+
+// WithOptions applies all the options from the preset, so they can be
+// reused and combined with other options and presets. For example:
+//
+//	var RecursiveFolders = Options{Recursively, Only(Folder)}
+//
+//	Find(ctx, where, ts, WithOptions(RecursiveFolders), Max(10))
+func WithOptions(opts Options) optFunc {
+	return func(o *options) {
+		for _, fn := range opts {
+			fn(o)
+		}
+	}
 }
 
 // MatchAny returns true if any of the given templates match the string.
@@ -223,6 +1923,18 @@ func MatchAny(ts Templates, str string) bool {
 	return false
 }
 
+// MatchIndex returns index of the first template, which matches
+// the string, or -1 if none of them match.
+func MatchIndex(ts Templates, str string) int {
+	for i, t := range ts {
+		if t.Match(str) {
+			return i
+		}
+	}
+
+	return -1
+}
+
 // MatchAll returns true if all of the given templates match the string.
 func MatchAll(ts Templates, str string) bool {
 	for _, t := range ts {
@@ -0,0 +1,158 @@
+package find
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrTemplateSyntax = errors.New("invalid template syntax")
+
+// parser is a recursive descent parser of the template string:
+//
+//	expr   = term { '|' term }
+//	term   = factor { '&' factor }
+//	factor = [ '!' ] '(' expr ')' | leaf
+type parser struct {
+	str   string
+	pos   int
+	depth int
+	err   error
+}
+
+// parse parses the whole string. Parsing does not stop on errors,
+// so the result can still be used in non-validating mode.
+func (p *parser) parse() *Template {
+	return p.expr()
+}
+
+func (p *parser) fail(format string, args ...any) {
+	if p.err == nil {
+		p.err = fmt.Errorf(
+			"%w: %s in %q",
+			ErrTemplateSyntax, fmt.Sprintf(format, args...), p.str,
+		)
+	}
+}
+
+func (p *parser) peek(c byte) bool {
+	return p.pos < len(p.str) && p.str[p.pos] == c
+}
+
+func (p *parser) expr() *Template {
+	terms := []*Template{p.term()}
+
+	for p.peek('|') {
+		p.pos++
+		terms = append(terms, p.term())
+	}
+
+	if len(terms) == 1 {
+		return terms[0]
+	}
+
+	// Each element of the '|' chain must not have its own '&' chain,
+	// otherwise they would be evaluated together.
+	for i, t := range terms {
+		if t.and != nil {
+			terms[i] = &Template{group: t}
+		}
+	}
+
+	for i := 0; i < len(terms)-1; i++ {
+		terms[i].or = terms[i+1]
+	}
+
+	return terms[0]
+}
+
+func (p *parser) term() *Template {
+	head := p.factor()
+
+	for last := head; p.peek('&'); last = last.and {
+		p.pos++
+		last.and = p.factor()
+	}
+
+	return head
+}
+
+func (p *parser) factor() *Template {
+	start, depth, err := p.pos, p.depth, p.err
+
+	if p.peek('!') {
+		p.pos++
+	}
+
+	if p.peek('(') {
+		p.pos++
+		p.depth++
+
+		t := &Template{group: p.expr(), not: p.str[start] == '!'}
+
+		if p.peek(')') {
+			p.pos++
+			p.depth--
+		} else {
+			p.fail("unbalanced parentheses")
+		}
+
+		if p.pos == len(p.str) || p.peek('&') || p.peek('|') ||
+			p.peek(')') && p.depth > 0 {
+			return t
+		}
+
+		// Parentheses are followed by other characters, so
+		// they are part of the name e.g., '(1).txt'.
+		p.pos, p.depth, p.err = start, depth, err
+	}
+
+	return p.leaf(start)
+}
+
+// leaf parses a single template from the start position until the
+// first unescaped operator or the closing parenthesis of the group.
+func (p *parser) leaf(start int) *Template {
+	p.pos = start
+	balance := 0
+
+loop:
+	for ; p.pos < len(p.str); p.pos++ {
+		switch p.str[p.pos] {
+		case '\\':
+			p.pos++
+		case '&', '|':
+			break loop
+		case '(':
+			balance++
+		case ')':
+			switch {
+			case balance > 0:
+				balance--
+			case p.depth > 0:
+				break loop
+			default:
+				p.fail("unbalanced parentheses")
+			}
+		}
+	}
+
+	if balance > 0 {
+		p.fail("unbalanced parentheses")
+	}
+
+	// Escape at the very end of the string.
+	if p.pos > len(p.str) {
+		p.pos = len(p.str)
+	}
+
+	switch str := p.str[start:p.pos]; {
+	case p.str == "":
+		p.fail("empty template")
+	case str == "":
+		p.fail("missing pattern at position %d", start)
+	case str == "!":
+		p.fail("negation without pattern at position %d", start)
+	}
+
+	return parse(p.str[start:p.pos])
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package find
+
+import "io/fs"
+
+// inodeOf always reports false, since inode numbers are not
+// available on this platform.
+func inodeOf(fs.FileInfo) (inode, bool) { return inode{}, false }
+
+// deviceOf always reports false, since device ids are not available
+// on this platform.
+func deviceOf(fs.FileInfo) (uint64, bool) { return 0, false }
+
+// ownerOf always reports false, since owner ids are not available
+// on this platform.
+func ownerOf(fs.FileInfo) (uid, gid int, ok bool) { return 0, 0, false }
@@ -0,0 +1,39 @@
+//go:build unix
+
+package find
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// inodeOf returns device and inode numbers of the file, which has
+// several hardlinks.
+func inodeOf(info fs.FileInfo) (inode, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || st.Nlink < 2 {
+		return inode{}, false
+	}
+
+	return inode{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
+
+// deviceOf returns id of the device, which contains the file.
+func deviceOf(info fs.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(st.Dev), true
+}
+
+// ownerOf returns user and group ids of the file owner.
+func ownerOf(info fs.FileInfo) (uid, gid int, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+
+	return int(st.Uid), int(st.Gid), true
+}
@@ -0,0 +1,119 @@
+//go:build unix
+
+package find
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestWithHardlinkDedup(t *testing.T) {
+	root := makeTree(t, "a/1.txt", "b/", "2.txt")
+
+	err := os.Link(filepath.Join(root, "a", "1.txt"), filepath.Join(root, "b", "1.txt"))
+	if err != nil {
+		t.Skipf("hardlinks are not supported: %v", err)
+	}
+
+	got := mustFind(t, root, "*.txt", Recursively)
+	assertPaths(t, got, "2.txt", "a/1.txt", "b/1.txt")
+
+	got = mustFind(t, root, "*.txt", Recursively, WithHardlinkDedup)
+	assertPaths(t, got, "2.txt", "a/1.txt")
+}
+
+func TestWithOwner(t *testing.T) {
+	root := makeTree(t, "a/1.txt", "2.txt")
+
+	uid, gid := os.Getuid(), os.Getgid()
+
+	got := mustFind(t, root, "*", Recursively, WithOwner(uid))
+	assertPaths(t, got, "2.txt", "a", "a/1.txt")
+
+	got = mustFind(t, root, "*", Recursively, WithOwner(uid), WithGroup(gid))
+	assertPaths(t, got, "2.txt", "a", "a/1.txt")
+
+	got = mustFind(t, root, "*", Recursively, WithOwner(uid+1))
+	assertPaths(t, got)
+
+	got = mustFind(t, root, "*", Recursively, WithGroup(gid+1))
+	assertPaths(t, got)
+}
+
+func TestSameFilesystem(t *testing.T) {
+	// Look for a non-empty mount point in /dev e.g., /dev/pts or /dev/shm.
+	const root = "/dev"
+
+	rootInfo, err := os.Stat(root)
+	if err != nil {
+		t.Skipf("%s is not available: %v", root, err)
+	}
+
+	rootDev, _ := deviceOf(rootInfo)
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Skipf("%s cannot be read: %v", root, err)
+	}
+
+	var mount string
+
+	for _, e := range entries {
+		p := filepath.Join(root, e.Name())
+
+		info, err := os.Lstat(p)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+
+		if dev, ok := deviceOf(info); ok && dev != rootDev {
+			if sub, err := os.ReadDir(p); err == nil && len(sub) > 0 {
+				mount = p
+
+				break
+			}
+		}
+	}
+
+	if mount == "" {
+		t.Skip("no mount point found in", root)
+	}
+
+	inside := func(res []string) bool {
+		for _, p := range res {
+			if strings.HasPrefix(p, mount+string(filepath.Separator)) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	opts := Options{Recursively, MaxDepth(1), WithErrorsSkip}
+
+	res, err := Find(context.Background(), root, "*", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !inside(res) {
+		t.Fatalf("expected entries of %s without the option", mount)
+	}
+
+	res, err = Find(context.Background(), root, "*", append(opts, SameFilesystem)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inside(res) {
+		t.Errorf("unexpected entries of %s", mount)
+	}
+
+	if !slices.Contains(res, mount) {
+		t.Errorf("mount point %s itself should be found", mount)
+	}
+}
@@ -1,21 +1,86 @@
 package find
 
 import (
+	"bufio"
+	"fmt"
+	"io"
 	"os"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
 // String representation of the current system path separator.
 var pathSeparator = string(os.PathSeparator)
 
+// Path separator in templates. Matched paths are converted to use it,
+// so templates are the same on all platforms.
+const separator = "/"
+
+// toSlash replaces platform separators in p with [separator].
+func toSlash(p string) string {
+	if pathSeparator == separator {
+		return p
+	}
+
+	return strings.ReplaceAll(p, pathSeparator, separator)
+}
+
+// Kinds of the template pattern tokens.
+const (
+	tokLiteral uint8 = iota
+	tokOne
+	tokStar
+	// Double star, which matches across path separators.
+	tokAny
+	// Double star followed by the separator, which matches any
+	// number of folders, including none.
+	tokDirs
+)
+
+// token is a part of the template pattern, which contains wildcards
+// other than leading or trailing '*'.
+type token struct {
+	kind uint8
+	lit  string
+}
+
 // Template is a parsed version of each Find filter.
 type Template struct {
 	and         *Template
 	or          *Template
+	group       *Template
+	re          *regexp.Regexp
+	glob        string
+	tokens      []token
 	base        string
 	not         bool
 	strictLeft  bool
 	strictRight bool
+	// Pattern surrounded by separators, which matches whole path
+	// components anywhere in the path.
+	segment bool
+	// Base is compared with the whole string, set by [ExactName].
+	literal     bool
+	insensitive bool
+	// Original form of the brace expansion, which is kept by String,
+	// since empty alternatives cannot be shown as a group.
+	braces string
+	// '&' chains are matched against the nested path components,
+	// set by [NestedAnd].
+	nest *nesting
+}
+
+// nesting describes the matched strings for [NestedAnd].
+type nesting struct {
+	// Templates without '&' chain match the whole string, not only
+	// the last component.
+	whole bool
+	// Number of the leading components of the root, which are not
+	// matched by the chains.
+	skip int
 }
 
 // NewTemplate creates new Template from the given string.
@@ -26,44 +91,389 @@ type Template struct {
 //	str      - means that searched path should be str
 //	*str     - means that searched path should ends with str
 //	str*     - means that searched path should starts with str
+//	st?r     - means that '?' matches any single character except
+//	           path separator
+//	st*r     - means that '*' in the middle matches any number of
+//	           characters except path separator
+//	/str/    - means that searched path should contain str as a whole
+//	           path component e.g., '/test/' matches 'a/test/b',
+//	           but not 'a/mytest/b'
+//	a/**/b   - means that '**' matches any number of characters
+//	           including path separators, '**/' matches any number
+//	           of folders or none of them
 //	!*str*   - means that searched path should not contain str
 //	!str     - means that searched path should not be str
 //	!*str    - means that searched path should not end with str
 //	!str*    - means that searched path should not start with str
 //	str&str1 - means that searched path should be both str and str1
 //	str|str1 - means that searched path should be str or str1
+//	(a|b)&c  - means that searched path should be c and either a or b
+//	!(a|b)   - means that searched path should be neither a nor b
+//	*.{a,b}  - means that searched path should be either *.a or *.b
 //
-// Option '&' defines nested paths e.g., '*str*&*str1*' - Find will search
-// for 'str' first and if it was found 'str1' inside it.
+// Option '&' requires all the patterns to match the same name. With
+// [NestedAnd] it defines nested paths e.g., '*str*&*str1*' - Find will
+// search for 'str' first and if it was found 'str1' inside it.
 //
-// Options '|' and '&' can contain as many elements as you need.
+// Options '|' and '&' can contain as many elements as you need. Option '&'
+// binds tighter than '|', so 'a|b&c' is the same as 'a|(b&c)'. Parentheses
+// in the middle of the name e.g., 'file(1).txt' are matched literally.
+//
+// Braces can be nested and contain empty alternatives e.g., 'a{b,{c,}}'
+// expands to 'ab', 'ac' and 'a'. Braces without comma inside are matched
+// literally.
+//
+// Special characters '*', '?', '&', '|', '!', '{', '}', ',', '(' and ')'
+// can be escaped with backslash to be matched literally e.g.,
+// 'report\*final' matches only 'report*final'.
+//
+// NewTemplate does not report syntax errors: unbalanced parentheses are
+// matched literally and empty patterns e.g., an empty string, 'a&' or '!' never
+// match. Use [CompileTemplate] to validate the string.
 func NewTemplate(str string) *Template {
-	var t *Template
+	t, _ := CompileTemplate(str)
 
-	sep := strings.IndexFunc(str, func(r rune) bool {
-		if r == '&' || r == '|' {
-			return true
+	return t
+}
+
+// CompileTemplate acts the same way as [NewTemplate], but returns
+// [ErrTemplateSyntax] if the string cannot be parsed: it is empty, has
+// unbalanced parentheses, '&' or '|' without pattern on any side or
+// '!' without pattern after it.
+func CompileTemplate(str string) (*Template, error) {
+	p := &parser{str: str}
+
+	return p.parse(), p.err
+}
+
+// NewRegexpTemplate creates new Template, which matches strings with
+// the given regular expression. Returns an error if expr cannot be compiled.
+//
+// Template can be chained with others via [Template.And] and [Template.Or].
+//
+// Note: [Insensitive] lowercases only the matched strings, not the
+// expression itself, so add the '(?i)' flag to expr to match regardless
+// of case.
+func NewRegexpTemplate(expr string) (*Template, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template{re: re}, nil
+}
+
+// NewGlobTemplate creates new Template, which matches strings with the
+// glob pattern the same way as [path.Match] e.g., 'file[0-9].txt'.
+// Returns [ErrTemplateSyntax] wrapping [path.ErrBadPattern] if pattern
+// is malformed.
+//
+// Template can be chained with others via [Template.And] and [Template.Or].
+func NewGlobTemplate(pattern string) (*Template, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("%w: %w: %q", ErrTemplateSyntax, err, pattern)
+	}
+
+	return &Template{glob: pattern}, nil
+}
+
+// ByExtension creates new case insensitive Template, which matches names
+// with any of the given extensions. Leading dot is optional, so both "go"
+// and ".go" match 'main.go'. Compound extensions e.g., "tar.gz" are
+// supported as well.
+func ByExtension(exts ...string) *Template {
+	alts := make([]string, 0, len(exts))
+
+	for _, ext := range exts {
+		if ext = strings.TrimPrefix(ext, "."); ext != "" {
+			alts = append(alts, "*."+escape(ext))
 		}
+	}
 
-		return false
-	})
+	return NewTemplate(strings.Join(alts, "|")).Insensitive()
+}
+
+// escape escapes all the special characters of the template syntax,
+// so str is matched literally.
+func escape(str string) string {
+	var sb strings.Builder
+
+	for _, r := range str {
+		if strings.ContainsRune(`\*?&|!{},()`, r) {
+			sb.WriteByte('\\')
+		}
+
+		sb.WriteRune(r)
+	}
 
-	if sep == -1 {
-		return parse(str)
+	return sb.String()
+}
+
+// And returns new Template, which matches if both t and o match.
+func (t *Template) And(o *Template) *Template {
+	return &Template{group: t, and: o}
+}
+
+// Or returns new Template, which matches if either t or o matches.
+func (t *Template) Or(o *Template) *Template {
+	return &Template{group: t, or: o}
+}
+
+// And returns new Template, which matches if all the given templates
+// match. Each template is matched as a whole, so expressions can be
+// nested e.g., Or(And(a, b), c) means '(a&b)|c'.
+func And(t *Template, ts ...*Template) *Template {
+	return combine(t, ts, false)
+}
+
+// Or returns new Template, which matches if any of the given templates
+// matches. Each template is matched as a whole, so expressions can be
+// nested e.g., And(Or(a, b), c) means '(a|b)&c'.
+func Or(t *Template, ts ...*Template) *Template {
+	return combine(t, ts, true)
+}
+
+// combine chains templates with '&' or '|'. Each of them is wrapped in
+// the group, so their own chains do not mix with the new one.
+func combine(t *Template, ts []*Template, or bool) *Template {
+	head := &Template{group: t}
+
+	last := head
+	for _, next := range ts {
+		node := &Template{group: next}
+
+		if or {
+			last.or = node
+		} else {
+			last.and = node
+		}
+
+		last = node
+	}
+
+	return &Template{group: head}
+}
+
+// Insensitive makes template and all its nested ones case insensitive,
+// so [Template.Match] lowercases the given string before matching.
+// Returns t.
+func (t *Template) Insensitive() *Template {
+	if t == nil || t.insensitive {
+		return t
+	}
+
+	t.insensitive = true
+	t.base = strings.ToLower(t.base)
+	t.glob = strings.ToLower(t.glob)
+	t.braces = strings.ToLower(t.braces)
+
+	for i := range t.tokens {
+		t.tokens[i].lit = strings.ToLower(t.tokens[i].lit)
 	}
 
-	switch str[sep] {
-	case '&':
-		t = parse(str[:sep])
-		t.and = NewTemplate(str[sep+1:])
-	case '|':
-		t = parse(str[:sep])
-		t.or = NewTemplate(str[sep+1:])
+	if t.re != nil {
+		t.re = regexp.MustCompile("(?i)" + t.re.String())
 	}
 
+	t.group.Insensitive()
+	t.and.Insensitive()
+	t.or.Insensitive()
+
 	return t
 }
 
+// fold returns a copy of the template and its nested ones with fn
+// applied to the patterns. Regular expressions are kept as is, the same
+// way as they are not changed by [Insensitive].
+func (t *Template) fold(fn caseFunc) *Template {
+	if t == nil {
+		return nil
+	}
+
+	c := *t
+	c.base = fn.apply(c.base)
+	c.glob = fn.apply(c.glob)
+	c.braces = fn.apply(c.braces)
+	c.tokens = slices.Clone(c.tokens)
+
+	for i := range c.tokens {
+		c.tokens[i].lit = fn.apply(c.tokens[i].lit)
+	}
+
+	c.group = t.group.fold(fn)
+	c.and = t.and.fold(fn)
+	c.or = t.or.fold(fn)
+
+	return &c
+}
+
+// String returns canonical textual form of the template, which can be
+// parsed back with [NewTemplate]. For simple patterns it is the same as
+// the original string, brace expansions are kept as is. Regular
+// expression templates are shown as their expressions.
+func (t *Template) String() string {
+	var sb strings.Builder
+
+	t.write(&sb)
+
+	return sb.String()
+}
+
+func (t *Template) write(sb *strings.Builder) {
+	switch {
+	case t.re != nil:
+		sb.WriteString(t.re.String())
+	case t.glob != "":
+		sb.WriteString(t.glob)
+	case t.braces != "":
+		if t.not {
+			sb.WriteByte('!')
+		}
+
+		sb.WriteString(t.braces)
+	case t.group != nil:
+		// Group without '|' inside can be shown without parentheses,
+		// since '&' binds tighter anyway.
+		parens := t.not || t.group.or != nil
+
+		if t.not {
+			sb.WriteByte('!')
+		}
+
+		if parens {
+			sb.WriteByte('(')
+		}
+
+		t.group.write(sb)
+
+		if parens {
+			sb.WriteByte(')')
+		}
+	default:
+		if t.not {
+			sb.WriteByte('!')
+		}
+
+		if t.base == "*" && t.tokens == nil {
+			sb.WriteByte('*')
+
+			break
+		}
+
+		if t.segment {
+			sb.WriteString(separator + t.base + separator)
+
+			break
+		}
+
+		if !t.strictLeft {
+			sb.WriteByte('*')
+		}
+
+		sb.WriteString(t.base)
+
+		if !t.strictRight {
+			sb.WriteByte('*')
+		}
+	}
+
+	switch {
+	case t.and != nil:
+		sb.WriteByte('&')
+
+		// Operand with its own '|' chain must stay together, since
+		// '&' binds tighter.
+		if t.and.or != nil {
+			sb.WriteByte('(')
+			t.and.write(sb)
+			sb.WriteByte(')')
+
+			break
+		}
+
+		t.and.write(sb)
+	case t.or != nil:
+		sb.WriteByte('|')
+		t.or.write(sb)
+	}
+}
+
+// Validate returns [ErrTemplateSyntax] if template or any of its nested
+// ones can match only an empty string e.g., it was created by
+// [NewTemplate] from an empty string, '!' or '&' and '|' without pattern
+// on any side. Such templates never match any name. [CompileTemplate]
+// and [Find] already report these errors during parsing.
+func (t *Template) Validate() error {
+	if t == nil {
+		return fmt.Errorf("%w: nil template", ErrTemplateSyntax)
+	}
+
+	if reason := t.degenerate(); reason != "" {
+		return fmt.Errorf("%w: %s in %q", ErrTemplateSyntax, reason, t)
+	}
+
+	return nil
+}
+
+// degenerate returns the reason, why template or its nested ones
+// match only an empty string, or an empty string if they are valid.
+func (t *Template) degenerate() string {
+	if t.re == nil && t.glob == "" && t.group == nil && t.base == "" {
+		if t.not {
+			return "'!' without pattern"
+		}
+
+		return "empty pattern"
+	}
+
+	for _, nested := range []*Template{t.group, t.and, t.or} {
+		if nested == nil {
+			continue
+		}
+
+		if reason := nested.degenerate(); reason != "" {
+			return reason
+		}
+	}
+
+	return ""
+}
+
+// key returns the canonical form of the template, which is equal only
+// for templates matching the same strings.
+func (t *Template) key() string {
+	var sb strings.Builder
+
+	t.write(&sb)
+	sb.WriteByte(0)
+	t.writeKinds(&sb)
+
+	return sb.String()
+}
+
+// writeKinds writes the kinds of the template and its nested ones,
+// since regexp, glob and template can have the same string form.
+func (t *Template) writeKinds(sb *strings.Builder) {
+	switch {
+	case t.re != nil:
+		sb.WriteByte('r')
+	case t.glob != "":
+		sb.WriteByte('g')
+	default:
+		sb.WriteByte('t')
+	}
+
+	if t.insensitive {
+		sb.WriteByte('i')
+	}
+
+	for _, nested := range []*Template{t.group, t.and, t.or} {
+		if nested != nil {
+			nested.writeKinds(sb)
+		}
+	}
+}
+
 // parse parses string into the Template.
 func parse(str string) *Template {
 	t := &Template{}
@@ -71,34 +481,264 @@ func parse(str string) *Template {
 	t.not = strings.HasPrefix(str, "!")
 	str = strings.TrimPrefix(str, "!")
 
+	// Brace expansion creates a group of alternatives, which
+	// should match as a whole, so 'Not' negates all of them.
+	if alts := expandBraces(str); len(alts) > 1 {
+		t.braces = str
+		t.group = parse(alts[0])
+
+		last := t.group
+		for _, alt := range alts[1:] {
+			last.or = parse(alt)
+			last = last.or
+		}
+
+		return t
+	}
+
 	// If searched string is '*', then it will match
 	// any path it encounters. 'Not' will be ignored
 	// in this case.
-	if str == "*" {
+	if str == "*" || str == "**" {
 		t.strictLeft = false
 		t.strictRight = false
-		t.base = str
+		t.base = "*"
+
+		return t
+	}
+
+	if isSegment(str) {
+		t.segment = true
+		t.strictLeft = true
+		t.strictRight = true
+		t.base = str[len(separator) : len(str)-len(separator)]
+
+		// Tokens check every occurrence, not just the first one.
+		if t.tokens = tokenize(t.base); t.tokens == nil {
+			t.tokens = []token{{kind: tokLiteral, lit: t.base}}
+		}
 
 		return t
 	}
 
-	t.strictLeft = !strings.HasPrefix(str, "*")
-	str = strings.TrimPrefix(str, "*")
-	t.strictRight = !strings.HasSuffix(str, "*")
-	t.base = strings.TrimSuffix(str, "*")
+	// Leading and trailing '**' are kept as tokens, since they
+	// match across path separators unlike the single '*'.
+	if strings.HasPrefix(str, "**") {
+		t.strictLeft = true
+	} else {
+		t.strictLeft = !strings.HasPrefix(str, "*")
+		str = strings.TrimPrefix(str, "*")
+	}
+
+	switch {
+	case strings.HasSuffix(str, "**") && !isEscaped(str, len(str)-2):
+		t.strictRight = true
+	default:
+		t.strictRight = !strings.HasSuffix(str, "*") ||
+			isEscaped(str, len(str)-1)
+
+		if !t.strictRight {
+			str = str[:len(str)-1]
+		}
+	}
+
+	t.base = str
+	t.tokens = tokenize(t.base)
 
 	return t
 }
 
-// Match checks if given str matches the [Template].
+// isSegment reports if str is surrounded by path separators
+// e.g., '/test/'.
+func isSegment(str string) bool {
+	return len(str) > 2*len(separator) &&
+		strings.HasPrefix(str, separator) &&
+		strings.HasSuffix(str, separator) &&
+		!isEscaped(str, len(str)-len(separator))
+}
+
+// tokenize splits pattern into tokens. Returns nil if pattern does not
+// contain any wildcards or escaped characters, so it can be matched as
+// a plain substring.
+func tokenize(str string) []token {
+	if !strings.ContainsAny(str, "?*\\") {
+		return nil
+	}
+
+	var (
+		tokens []token
+		lit    strings.Builder
+	)
+
+	flush := func() {
+		if lit.Len() > 0 {
+			tokens = append(tokens, token{kind: tokLiteral, lit: lit.String()})
+			lit.Reset()
+		}
+	}
+
+	for i := 0; i < len(str); i++ {
+		switch {
+		case str[i] == '\\' && i+1 < len(str):
+			i++
+			lit.WriteByte(str[i])
+		case str[i] == '?':
+			flush()
+			tokens = append(tokens, token{kind: tokOne})
+		case str[i] == '*':
+			flush()
+
+			n := 1
+			for i+n < len(str) && str[i+n] == '*' {
+				n++
+			}
+
+			i += n - 1
+
+			switch {
+			case n == 1:
+				tokens = append(tokens, token{kind: tokStar})
+			case strings.HasPrefix(str[i+1:], separator):
+				i += len(separator)
+				tokens = append(tokens, token{kind: tokDirs})
+			default:
+				// Three or more stars in a row are the same as two.
+				tokens = append(tokens, token{kind: tokAny})
+			}
+		default:
+			lit.WriteByte(str[i])
+		}
+	}
+
+	flush()
+
+	return tokens
+}
+
+// expandBraces expands the first brace group with alternatives in str
+// and recursively all the following ones. Returns str as is if there is
+// nothing to expand.
+func expandBraces(str string) []string {
+	open, end, alts := -1, -1, []string(nil)
+
+	for i := 0; i < len(str) && alts == nil; i++ {
+		switch {
+		case str[i] == '\\':
+			i++
+		case str[i] == '{':
+			end, alts = splitBraces(str[i:])
+			open, end = i, i+end
+		}
+	}
+
+	if alts == nil {
+		return []string{str}
+	}
+
+	var res []string
+
+	for _, alt := range alts {
+		for _, suffix := range expandBraces(alt + str[end+1:]) {
+			res = append(res, str[:open]+suffix)
+		}
+	}
+
+	return res
+}
+
+// splitBraces splits content of the brace group at the start of str by
+// top level commas. Returns the index of the closing brace and nil
+// alternatives if group is not closed or does not contain commas.
+func splitBraces(str string) (int, []string) {
+	var (
+		alts  []string
+		depth int
+		from  = 1
+	)
+
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				if alts == nil {
+					return i, nil
+				}
+
+				return i, append(alts, str[from:i])
+			}
+		case ',':
+			if depth == 1 {
+				alts = append(alts, str[from:i])
+				from = i + 1
+			}
+		}
+	}
+
+	return -1, nil
+}
+
+// indexUnescaped returns the index of the first occurrence of any of
+// the chars in str, which is not escaped with backslash, or -1.
+func indexUnescaped(str, chars string) int {
+	for i := 0; i < len(str); i++ {
+		switch {
+		case str[i] == '\\':
+			i++
+		case strings.IndexByte(chars, str[i]) != -1:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// isEscaped reports if the character at position i is escaped
+// with backslash.
+func isEscaped(str string, i int) bool {
+	n := 0
+	for ; i > 0 && str[i-1] == '\\'; i-- {
+		n++
+	}
+
+	return n%2 == 1
+}
+
+// Match checks if given str matches the [Template]. Platform path
+// separators in str are matched as '/', so templates are portable.
 func (t *Template) Match(str string) bool {
 	var match bool
 
+	str = toSlash(str)
+
+	if t.nest != nil {
+		return t.matchNested(str)
+	}
+
+	if t.insensitive {
+		str = strings.ToLower(str)
+	}
+
 	switch {
+	case t.literal:
+		match = str == t.base
+	case t.re != nil:
+		match = t.re.MatchString(str) != t.not
+	case t.glob != "":
+		// Pattern was validated, so there is no error.
+		match, _ = path.Match(t.glob, str)
+	case t.group != nil:
+		match = t.group.Match(str) != t.not
 	case t.base == "":
 		return false
 	case t.base == "*":
 		match = true
+	case t.tokens != nil:
+		match = t.matchTokens(str) != t.not
 	case strings.Contains(str, t.base):
 		match = t.match(str)
 	case t.not:
@@ -120,17 +760,151 @@ func (t *Template) Match(str string) bool {
 	return match
 }
 
+// matchNested checks str with [NestedAnd]: '&' chains are matched
+// against its components below the root, the rest of the templates
+// match it the same way as without nesting.
+func (t *Template) matchNested(str string) bool {
+	parts := strings.Split(str, separator)
+	if len(parts) > t.nest.skip {
+		parts = parts[t.nest.skip:]
+	} else {
+		// Root itself has no components below it.
+		parts = []string{path.Base(str)}
+	}
+
+	target := ""
+	if t.nest.whole {
+		target = str
+	}
+
+	return t.matchPath(parts, target)
+}
+
+// matchPath checks if template matches path split into components.
+// Each template of the '&' chain matches the folders below the previous
+// one and the last one matches the rest of the path, groups and '|'
+// chains are matched the same way. Template outside of the chain
+// matches target or the last component if target is empty.
+func (t *Template) matchPath(parts []string, target string) bool {
+	if t.and != nil {
+		return t.matchChain(parts)
+	}
+
+	match := t.matchSelf(parts, target)
+	if !match && t.or != nil {
+		match = t.or.matchPath(parts, target)
+	}
+
+	return match
+}
+
+// matchSelf checks the template without its chains.
+func (t *Template) matchSelf(parts []string, target string) bool {
+	if t.group != nil {
+		return t.group.matchPath(parts, target) != t.not
+	}
+
+	if target == "" {
+		target = parts[len(parts)-1]
+	}
+
+	el := *t
+	el.and, el.or, el.nest = nil, nil, nil
+
+	return el.Match(target)
+}
+
+// matchChain checks the '&' chain. The earliest folder, which matches
+// the template, leaves the most of the path to the next ones.
+func (t *Template) matchChain(parts []string) bool {
+	start, last := 0, len(parts)-1
+
+	node := t
+	for ; node.and != nil; node = node.and {
+		el := *node
+		el.and = nil
+
+		i := start
+		for i < last && !el.matchPath(parts[start:i+1], "") {
+			i++
+		}
+
+		if i == last {
+			return false
+		}
+
+		start = i + 1
+	}
+
+	return node.matchPath(parts[start:], "")
+}
+
+// remaining returns the least number of path components, which must
+// follow the folder split into parts, so the template can match.
+func (t *Template) remaining(parts []string) int {
+	if t.and != nil {
+		return t.remainingChain(parts)
+	}
+
+	n := 1
+	if t.group != nil && !t.not {
+		n = t.group.remaining(parts)
+	}
+
+	if t.or != nil {
+		n = min(n, t.or.remaining(parts))
+	}
+
+	return n
+}
+
+// remainingChain acts the same way as remaining for the '&' chain.
+// Templates, which did not match any of the parts, need a folder each.
+func (t *Template) remainingChain(parts []string) int {
+	start := 0
+
+	node := t
+	for ; node.and != nil; node = node.and {
+		el := *node
+		el.and = nil
+
+		i := start
+		for i < len(parts) && !el.matchPath(parts[start:i+1], "") {
+			i++
+		}
+
+		if i == len(parts) {
+			n := 0
+			for ; node.and != nil; node = node.and {
+				n++
+			}
+
+			return n + node.remaining(nil)
+		}
+
+		start = i + 1
+	}
+
+	return node.remaining(parts[start:])
+}
+
+// match checks borders around the first occurrence of the base in str.
+// Another occurrence right after the first one counts as a border.
 func (t *Template) match(str string) bool {
 	match := true
-	sub := strings.Split(str, t.base)
 
-	left := len(sub) == 1 ||
-		sub[0] == "" ||
-		strings.HasSuffix(sub[0], pathSeparator)
+	i := strings.Index(str, t.base)
+	if i == -1 {
+		return t.not
+	}
+
+	before, after := str[:i], str[i+len(t.base):]
 
-	right := len(sub) == 1 ||
-		sub[1] == "" ||
-		strings.HasPrefix(sub[1], pathSeparator)
+	left := before == "" || strings.HasSuffix(before, separator)
+
+	right := after == "" ||
+		strings.HasPrefix(after, separator) ||
+		strings.HasPrefix(after, t.base)
 
 	switch {
 	case t.strictLeft && t.strictRight:
@@ -148,6 +922,105 @@ func (t *Template) match(str string) bool {
 	return match
 }
 
+// matchTokens checks if any part of str matches template tokens,
+// respecting strict left and right borders.
+func (t *Template) matchTokens(str string) bool {
+	for i := 0; i <= len(str); {
+		if (!t.strictLeft || isLeftBorder(str, i)) &&
+			t.matchFrom(t.tokens, str, i) {
+			return true
+		}
+
+		if i == len(str) {
+			break
+		}
+
+		_, size := utf8.DecodeRuneInString(str[i:])
+		i += size
+	}
+
+	return false
+}
+
+// matchFrom checks if tokens match str starting from position i.
+func (t *Template) matchFrom(tokens []token, str string, i int) bool {
+	if len(tokens) == 0 {
+		return !t.strictRight || isRightBorder(str, i)
+	}
+
+	switch tok := tokens[0]; tok.kind {
+	case tokLiteral:
+		if !strings.HasPrefix(str[i:], tok.lit) {
+			return false
+		}
+
+		return t.matchFrom(tokens[1:], str, i+len(tok.lit))
+	case tokOne:
+		if i == len(str) || strings.HasPrefix(str[i:], separator) {
+			return false
+		}
+
+		_, size := utf8.DecodeRuneInString(str[i:])
+
+		return t.matchFrom(tokens[1:], str, i+size)
+	case tokStar:
+		for {
+			if t.matchFrom(tokens[1:], str, i) {
+				return true
+			}
+
+			if i == len(str) || strings.HasPrefix(str[i:], separator) {
+				return false
+			}
+
+			_, size := utf8.DecodeRuneInString(str[i:])
+			i += size
+		}
+	case tokAny:
+		for {
+			if t.matchFrom(tokens[1:], str, i) {
+				return true
+			}
+
+			if i == len(str) {
+				return false
+			}
+
+			_, size := utf8.DecodeRuneInString(str[i:])
+			i += size
+		}
+	case tokDirs:
+		// No folders at all.
+		if t.matchFrom(tokens[1:], str, i) {
+			return true
+		}
+
+		for i < len(str) {
+			_, size := utf8.DecodeRuneInString(str[i:])
+			i += size
+
+			if strings.HasSuffix(str[:i], separator) &&
+				t.matchFrom(tokens[1:], str, i) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// isLeftBorder reports if position i is the start of str or
+// follows path separator.
+func isLeftBorder(str string, i int) bool {
+	return i == 0 || strings.HasSuffix(str[:i], separator)
+}
+
+// isRightBorder reports if position i is the end of str or
+// precedes path separator.
+func isRightBorder(str string, i int) bool {
+	return i == len(str) || strings.HasPrefix(str[i:], separator)
+}
+
 type Templates []*Template
 
 // NewTemplates parses slice of strings into slice of Templates.
@@ -159,3 +1032,111 @@ func NewTemplates(t []string) Templates {
 
 	return ts
 }
+
+// TemplatesFromFile reads templates from the file, one per line. Blank
+// lines and lines starting with '#' are skipped, leading and trailing
+// spaces are trimmed. Returns [ErrTemplateSyntax] with the line number
+// if template cannot be parsed.
+func TemplatesFromFile(name string) (Templates, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var ts Templates
+
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		t, err := CompileTemplate(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, n, err)
+		}
+
+		ts = append(ts, t)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return ts, nil
+}
+
+// Add parses the string with [NewTemplate] and appends it
+// to the templates.
+func (ts *Templates) Add(str string) { ts.AddTemplate(NewTemplate(str)) }
+
+// AddTemplate appends already created template to the templates.
+func (ts *Templates) AddTemplate(t *Template) { *ts = append(*ts, t) }
+
+// Remove deletes all the templates equal to the parsed string and
+// reports if any of them was removed.
+func (ts *Templates) Remove(str string) bool {
+	s := NewTemplate(str).String()
+	n := len(*ts)
+
+	*ts = slices.DeleteFunc(*ts, func(t *Template) bool {
+		return t.String() == s
+	})
+
+	return len(*ts) != n
+}
+
+// Dedup returns templates without duplicates, only the first of the
+// equal templates is kept. Templates are equal if they have the same
+// [Template.String] form and kind e.g., regexp template never equals
+// the parsed one.
+func (ts Templates) Dedup() Templates {
+	seen := make(map[string]struct{}, len(ts))
+	res := make(Templates, 0, len(ts))
+
+	for _, t := range ts {
+		k := t.key()
+		if _, ok := seen[k]; ok {
+			continue
+		}
+
+		seen[k] = struct{}{}
+		res = append(res, t)
+	}
+
+	return res
+}
+
+// FilterReader reads newline delimited names from r and writes the ones,
+// which match the templates, to w. Templates are combined with fn, nil
+// fn means [MatchAny]. Names are matched as is, the same way as with
+// [Template.Match].
+func (ts Templates) FilterReader(r io.Reader, w io.Writer, fn MatchFunc) error {
+	if fn == nil {
+		fn = MatchAny
+	}
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		name := strings.TrimSuffix(sc.Text(), "\r")
+		if name == "" || !fn(ts, name) {
+			continue
+		}
+
+		if _, err := fmt.Fprintln(w, name); err != nil {
+			return err
+		}
+	}
+
+	return sc.Err()
+}
+
+// MatchAny returns true if any of the templates match the string.
+// Same as [MatchAny].
+func (ts Templates) MatchAny(str string) bool { return MatchAny(ts, str) }
+
+// MatchAll returns true if all of the templates match the string.
+// Same as [MatchAll].
+func (ts Templates) MatchAll(str string) bool { return MatchAll(ts, str) }
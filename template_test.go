@@ -0,0 +1,877 @@
+package find
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewRegexpTemplate(t *testing.T) {
+	re, err := NewRegexpTemplate(`^log-\d{4}-\d{2}\.txt$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		str  string
+		want bool
+	}{
+		{"log-2024-01.txt", true},
+		{"log-24-01.txt", false},
+		{"log-2024-01.txt.bak", false},
+	}
+
+	for _, tt := range tests {
+		if got := re.Match(tt.str); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.str, got, tt.want)
+		}
+	}
+
+	if _, err := NewRegexpTemplate(`log-(\d`); err == nil {
+		t.Error("expected compile error")
+	}
+}
+
+func TestNewRegexpTemplate_chain(t *testing.T) {
+	re, err := NewRegexpTemplate(`^\d+$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	or := re.Or(NewTemplate("*.txt"))
+
+	for str, want := range map[string]bool{
+		"123":     true,
+		"a.txt":   true,
+		"a.md":    false,
+		"12a.txt": true,
+	} {
+		if got := or.Match(str); got != want {
+			t.Errorf("Or: Match(%q) = %v, want %v", str, got, want)
+		}
+	}
+
+	re, err = NewRegexpTemplate(`(?i)^readme`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	and := re.And(NewTemplate("*.md"))
+
+	for str, want := range map[string]bool{
+		"README.md":  true,
+		"readme.md":  true,
+		"README.txt": false,
+		"notes.md":   false,
+	} {
+		if got := and.Match(str); got != want {
+			t.Errorf("And: Match(%q) = %v, want %v", str, got, want)
+		}
+	}
+}
+
+func TestTemplate_singleCharWildcard(t *testing.T) {
+	tests := []struct {
+		template string
+		str      string
+		want     bool
+	}{
+		{"?ile.txt", "file.txt", true},
+		{"?ile.txt", "ffile.txt", false},
+		{"?ile.txt", "ile.txt", false},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"file?.txt", "file.txt", false},
+		{"file.tx?", "file.txt", true},
+		{"file.tx?", "file.tx", false},
+		{"f??e.txt", "file.txt", true},
+		{"file?.txt", "fileé.txt", true},
+		{"*dir?file*", "/root/dir/file", false},
+		{"*e?.txt", "some/file1.txt", true},
+		{"!file?.txt", "file1.txt", false},
+		{"!file?.txt", "file10.txt", true},
+	}
+
+	for _, tt := range tests {
+		if got := NewTemplate(tt.template).Match(tt.str); got != tt.want {
+			t.Errorf(
+				"NewTemplate(%q).Match(%q) = %v, want %v",
+				tt.template, tt.str, got, tt.want,
+			)
+		}
+	}
+}
+
+func TestTemplate_escape(t *testing.T) {
+	tests := []struct {
+		template string
+		str      string
+		want     bool
+	}{
+		{`report\*final`, "report*final", true},
+		{`report\*final`, "report-and-final", false},
+		{`\*final`, "*final", true},
+		{`\*final`, "report-final", false},
+		{`report\*`, "report*", true},
+		{`report\*`, "report-final", false},
+		{`*\**`, "report*final", true},
+		{`*\**`, "report-final", false},
+		{`a\&b`, "a&b", true},
+		{`a\&b`, "a", false},
+		{`a\|b`, "a|b", true},
+		{`a\|b`, "b", false},
+		{`\!name`, "!name", true},
+		{`\!name`, "other", false},
+		{`file\?.txt`, "file?.txt", true},
+		{`file\?.txt`, "file1.txt", false},
+		{`dir\\*`, `dir\file`, true},
+	}
+
+	for _, tt := range tests {
+		if got := NewTemplate(tt.template).Match(tt.str); got != tt.want {
+			t.Errorf(
+				"NewTemplate(%q).Match(%q) = %v, want %v",
+				tt.template, tt.str, got, tt.want,
+			)
+		}
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		str  string
+		want []string
+	}{
+		{"*.go", []string{"*.go"}},
+		{"*.{jpg,png,gif}", []string{"*.jpg", "*.png", "*.gif"}},
+		{"{a,b}-{1,2}", []string{"a-1", "a-2", "b-1", "b-2"}},
+		{"a{b,{c,d}}", []string{"ab", "ac", "ad"}},
+		{"a{b,}", []string{"ab", "a"}},
+		{"a{b}", []string{"a{b}"}},
+		{"a{b,c", []string{"a{b,c"}},
+		{`a\{b,c}`, []string{`a\{b,c}`}},
+		{`a{b\,c,d}`, []string{`ab\,c`, "ad"}},
+	}
+
+	for _, tt := range tests {
+		got := expandBraces(tt.str)
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("expandBraces(%q) = %q, want %q", tt.str, got, tt.want)
+		}
+	}
+}
+
+func TestTemplate_braces(t *testing.T) {
+	tests := []struct {
+		template string
+		str      string
+		want     bool
+	}{
+		{"*.{jpg,png,gif}", "image.png", true},
+		{"*.{jpg,png,gif}", "image.bmp", false},
+		{"{img,pic}.{jpg,png}", "pic.jpg", true},
+		{"{img,pic}.{jpg,png}", "pic.gif", false},
+		{"{img,pic}.{jpg,png}", "doc.png", false},
+		{"file{,.bak}", "file", true},
+		{"file{,.bak}", "file.bak", true},
+		{"!*.{jpg,png}", "image.png", false},
+		{"!*.{jpg,png}", "notes.txt", true},
+		{"*.{jpg,png}&img*", "img.png", true},
+		{"*.{jpg,png}&img*", "pic.png", false},
+		{`\{a,b}`, "{a,b}", true},
+		{`\{a,b}`, "a", false},
+	}
+
+	for _, tt := range tests {
+		if got := NewTemplate(tt.template).Match(tt.str); got != tt.want {
+			t.Errorf(
+				"NewTemplate(%q).Match(%q) = %v, want %v",
+				tt.template, tt.str, got, tt.want,
+			)
+		}
+	}
+}
+
+func TestTemplate_interiorWildcard(t *testing.T) {
+	tests := []struct {
+		template string
+		str      string
+		want     bool
+	}{
+		{"a*b*c", "abc", true},
+		{"a*b*c", "a-b-c", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "acb", false},
+		{"a*b*c", "xabc", false},
+		{"a*b*c", "abcx", false},
+		{"a*b*c", "a/b/c", false},
+		{"*a*b*c", "xa-b-c", true},
+		{"a*b*c*", "a-b-cx", true},
+		{"*a*b*c*", "xxa-b-cxx", true},
+		{"src*test*.go", "src_test_main.go", true},
+		{"src*test*.go", "src_main.go", false},
+		{"src**test", "src-test", true},
+		{"*a*c", "/root/abc", true},
+		{"*a*c", "/root/ab/c", false},
+		{"a*c", "/root/abc", true},
+		{"a*c", "/root/xabc", false},
+		{"!a*b*c", "a-b-c", false},
+		{"!a*b*c", "a-c-b", true},
+		{"ä*ö", "äxyö", true},
+	}
+
+	for _, tt := range tests {
+		if got := NewTemplate(tt.template).Match(tt.str); got != tt.want {
+			t.Errorf(
+				"NewTemplate(%q).Match(%q) = %v, want %v",
+				tt.template, tt.str, got, tt.want,
+			)
+		}
+	}
+}
+
+func TestTemplate_doubleStar(t *testing.T) {
+	tests := []struct {
+		template string
+		str      string
+		want     bool
+	}{
+		{"src/**/test.go", "/root/src/test.go", true},
+		{"src/**/test.go", "/root/src/a/b/test.go", true},
+		{"src/**/test.go", "/root/src/a/mytest.go", false},
+		{"src/**/test.go", "/root/src/a/test.go/b", true},
+		{"src/**/test.go", "/root/mysrc/a/test.go", false},
+		{"src/*/test.go", "/root/src/a/b/test.go", false},
+		{"src**.go", "/root/src/a/b.go", true},
+		{"src**.go", "/root/src/a/b.gox", false},
+		{"**/test.go", "/root/src/test.go", true},
+		{"**/test.go", "/root/src/mytest.go", false},
+		{"**test.go", "/root/src/mytest.go", true},
+		{"src/**", "/root/src/a/b", true},
+		{"src/**", "/root/mysrc/a", false},
+		{"*src/**", "/root/mysrc/a", true},
+		{"src/***/test.go", "/root/src/a/test.go", true},
+		{"**", "/root/src", true},
+		{"!src/**/test.go", "/root/src/a/test.go", false},
+		{`src/\*\*/test.go`, "/root/src/a/test.go", false},
+		{`src/\*\*/test.go`, "/root/src/**/test.go", true},
+	}
+
+	for _, tt := range tests {
+		if got := NewTemplate(tt.template).Match(tt.str); got != tt.want {
+			t.Errorf(
+				"NewTemplate(%q).Match(%q) = %v, want %v",
+				tt.template, tt.str, got, tt.want,
+			)
+		}
+	}
+}
+
+func TestTemplate_segment(t *testing.T) {
+	tests := []struct {
+		template string
+		str      string
+		want     bool
+	}{
+		{"/test/", "test", true},
+		{"/test/", "/root/test", true},
+		{"/test/", "/root/test/a.go", true},
+		{"/test/", "/root/mytest/a.go", false},
+		{"/test/", "/root/tests/a.go", false},
+		{"/test/", "/root/mytest/test/a.go", true},
+		{"/te?t/", "/root/mytest/text/a.go", true},
+		{"/a/b/", "/root/a/b/c", true},
+		{"/a/b/", "/root/xa/b/c", false},
+		{"!/test/", "/root/test/a.go", false},
+		{"!/test/", "/root/mytest/a.go", true},
+		{"*/test/*", "/root/test/a.go", true},
+		{"*/test/*", "/root/mytest/a.go", false},
+	}
+
+	for _, tt := range tests {
+		if got := NewTemplate(tt.template).Match(tt.str); got != tt.want {
+			t.Errorf(
+				"NewTemplate(%q).Match(%q) = %v, want %v",
+				tt.template, tt.str, got, tt.want,
+			)
+		}
+	}
+
+	if got := NewTemplate("/test/|*.go").String(); got != "/test/|*.go" {
+		t.Errorf("unexpected string: %q", got)
+	}
+}
+
+func TestTemplate_precedence(t *testing.T) {
+	tests := []struct {
+		template string
+		str      string
+		want     bool
+	}{
+		{"*a*|*b*&*c*", "a", true},
+		{"*a*|*b*&*c*", "b", false},
+		{"*a*|*b*&*c*", "bc", true},
+		{"(*a*|*b*)&*c*", "a", false},
+		{"(*a*|*b*)&*c*", "ac", true},
+		{"*a*&*b*|*c*", "c", true},
+		{"*a*&(*b*|*c*)", "c", false},
+		{"*a*&(*b*|*c*)", "ac", true},
+		{"!(*a*|*b*)", "b", false},
+		{"!(*a*|*b*)", "c", true},
+		{"((*a*|*b*)&*c*)|*d*", "d", true},
+		{"((*a*|*b*)&*c*)|*d*", "bc", true},
+		{"((*a*|*b*)&*c*)|*d*", "b", false},
+		{"file(1).txt", "file(1).txt", true},
+		{"(1).txt", "(1).txt", true},
+		{"(file(1).txt|x)", "file(1).txt", true},
+		{`\(a\)`, "(a)", true},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := CompileTemplate(tt.template)
+		if err != nil {
+			t.Errorf("CompileTemplate(%q): %v", tt.template, err)
+
+			continue
+		}
+
+		if got := tmpl.Match(tt.str); got != tt.want {
+			t.Errorf(
+				"CompileTemplate(%q).Match(%q) = %v, want %v",
+				tt.template, tt.str, got, tt.want,
+			)
+		}
+	}
+}
+
+func TestCompileTemplate_unbalanced(t *testing.T) {
+	for _, str := range []string{
+		"(*a*|*b*",
+		"*a*)",
+		"(*a*|(*b*&*c*)",
+		"file(1.txt",
+	} {
+		_, err := CompileTemplate(str)
+		if !errors.Is(err, ErrTemplateSyntax) {
+			t.Errorf("CompileTemplate(%q) = %v, want %v", str, err, ErrTemplateSyntax)
+		}
+	}
+
+	_, err := Find(context.Background(), t.TempDir(), "(*a*")
+	if !errors.Is(err, ErrTemplateSyntax) {
+		t.Errorf("Find() = %v, want %v", err, ErrTemplateSyntax)
+	}
+}
+
+func TestTemplate_String(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"*", "*"},
+		{"str", "str"},
+		{"*str", "*str"},
+		{"str*", "str*"},
+		{"*str*", "*str*"},
+		{"!*foo*", "!*foo*"},
+		{"!str", "!str"},
+		{"!*foo*&bar", "!*foo*&bar"},
+		{"a|b|c", "a|b|c"},
+		{"a|b&c", "a|b&c"},
+		{"(a|b)&c", "(a|b)&c"},
+		{"!(a|b)", "!(a|b)"},
+		{"a&(b|!(c&d))", "a&(b|!(c&d))"},
+		{"s?r*t", "s?r*t"},
+		{`report\*final`, `report\*final`},
+		{"file(1).txt", "file(1).txt"},
+		{"*.{jpg,png}", "*.{jpg,png}"},
+		{"!{a,}", "!{a,}"},
+		{"(a&b)", "a&b"},
+	}
+
+	for _, tt := range tests {
+		got := NewTemplate(tt.template).String()
+		if got != tt.want {
+			t.Errorf("NewTemplate(%q).String() = %q, want %q",
+				tt.template, got, tt.want)
+		}
+	}
+
+	// String form must parse back into the template, which matches
+	// the same strings.
+	for _, tc := range []struct {
+		t    *Template
+		strs []string
+	}{
+		{
+			NewTemplate("a").And(NewTemplate("x|y")),
+			[]string{"y", "a", "x", "ax"},
+		},
+		{NewTemplate("{a,}"), []string{"a", "", "b"}},
+		{NewTemplate("*.{go,}"), []string{"x.go", "x.", "x"}},
+	} {
+		str := tc.t.String()
+
+		parsed, err := CompileTemplate(str)
+		if err != nil {
+			t.Errorf("CompileTemplate(%q): %v", str, err)
+
+			continue
+		}
+
+		for _, s := range tc.strs {
+			if got, want := parsed.Match(s), tc.t.Match(s); got != want {
+				t.Errorf("%q: Match(%q) = %t, want %t", str, s, got, want)
+			}
+		}
+	}
+
+	re, err := NewRegexpTemplate(`^\d+$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, want := re.Or(NewTemplate("*.txt")).String(), `^\d+$|*.txt`
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompileTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		msg      string
+	}{
+		{"", "empty template"},
+		{"a&", "missing pattern at position 2"},
+		{"|a", "missing pattern at position 0"},
+		{"a||b", "missing pattern at position 2"},
+		{"()", "missing pattern at position 1"},
+		{"!", "negation without pattern at position 0"},
+		{"a&!", "negation without pattern at position 2"},
+		{"(a|b", "unbalanced parentheses"},
+	}
+
+	for _, tt := range tests {
+		_, err := CompileTemplate(tt.template)
+		if !errors.Is(err, ErrTemplateSyntax) ||
+			!strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("CompileTemplate(%q) = %v, want %q", tt.template, err, tt.msg)
+		}
+	}
+
+	for _, str := range []string{"*", "a&b", `\!`, "!a", "!(a)", "*.{a,}"} {
+		if _, err := CompileTemplate(str); err != nil {
+			t.Errorf("CompileTemplate(%q) = %v, want nil", str, err)
+		}
+	}
+
+	_, err := Find(context.Background(), t.TempDir(), []string{"*.go", "a&"})
+	if !errors.Is(err, ErrTemplateSyntax) {
+		t.Errorf("Find() = %v, want %v", err, ErrTemplateSyntax)
+	}
+}
+
+func TestTemplate_Insensitive(t *testing.T) {
+	tests := []struct {
+		template string
+		str      string
+	}{
+		{"*README*", "readme.md"},
+		{"*readme*", "README.md"},
+		{"*.{JPG,png}", "photo.jpg"},
+		{"Make*&!*.BAK", "makefile"},
+		{"f?LE.TXT", "File.txt"},
+		{"*a*B*c", "xAbC"},
+	}
+
+	for _, tt := range tests {
+		if NewTemplate(tt.template).Match(tt.str) {
+			t.Errorf("NewTemplate(%q) should be case sensitive", tt.template)
+		}
+
+		if !NewTemplate(tt.template).Insensitive().Match(tt.str) {
+			t.Errorf(
+				"NewTemplate(%q).Insensitive().Match(%q) = false, want true",
+				tt.template, tt.str,
+			)
+		}
+	}
+
+	if NewTemplate("Make*&!*.BAK").Insensitive().Match("Makefile.bak") {
+		t.Error("negation should be case insensitive as well")
+	}
+
+	re, err := NewRegexpTemplate(`^LOG-\d+$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !re.Insensitive().Match("log-1") {
+		t.Error("regular expression should be case insensitive")
+	}
+}
+
+func TestByExtension(t *testing.T) {
+	tests := []struct {
+		exts []string
+		str  string
+		want bool
+	}{
+		{[]string{"go", "md"}, "main.go", true},
+		{[]string{"go", "md"}, "README.MD", true},
+		{[]string{".go"}, "main.go", true},
+		{[]string{"go"}, "main.gox", false},
+		{[]string{"go"}, "go", false},
+		{[]string{"gz"}, "archive.tar.gz", true},
+		{[]string{".tar.gz"}, "archive.tar.gz", true},
+		{[]string{"tar.gz"}, "archive.gz", false},
+		{[]string{"tar"}, "archive.tar.gz", false},
+		{[]string{"c++"}, "main.c++", true},
+		{[]string{"(1)"}, "file.(1)", true},
+		{nil, "main.go", false},
+		{[]string{"."}, "main.", false},
+	}
+
+	for _, tt := range tests {
+		if got := ByExtension(tt.exts...).Match(tt.str); got != tt.want {
+			t.Errorf(
+				"ByExtension(%q).Match(%q) = %t, want %t",
+				tt.exts, tt.str, got, tt.want,
+			)
+		}
+	}
+}
+
+func BenchmarkTemplate_Match(b *testing.B) {
+	ts := Templates{
+		NewTemplate("main.go"),
+		NewTemplate("*_test.go"),
+		NewTemplate("vendor*"),
+		NewTemplate("*cache*"),
+	}
+
+	names := []string{
+		"main.go", "find_test.go", "vendor", "README.md",
+		"go-build-cache", "template.go", "main.go.orig",
+	}
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		for _, name := range names {
+			MatchAny(ts, name)
+		}
+	}
+}
+
+func TestTemplates_methods(t *testing.T) {
+	ts := NewTemplates([]string{"*this*", "*that*"})
+
+	for _, str := range []string{"this", "that", "this and that", "other", ""} {
+		if got, want := ts.MatchAny(str), MatchAny(ts, str); got != want {
+			t.Errorf("MatchAny(%q) = %t, want %t", str, got, want)
+		}
+
+		if got, want := ts.MatchAll(str), MatchAll(ts, str); got != want {
+			t.Errorf("MatchAll(%q) = %t, want %t", str, got, want)
+		}
+	}
+}
+
+func TestTemplate_separators(t *testing.T) {
+	defer func(sep string) { pathSeparator = sep }(pathSeparator)
+
+	// Results with '/' and '\' as the platform separator. Backslash
+	// is a valid name character on unix.
+	tests := []struct {
+		template string
+		str      string
+		want     [2]bool
+	}{
+		{"src/*.go", "/root/src/main.go", [2]bool{true, true}},
+		{"src/*.go", `C:\root\src\main.go`, [2]bool{false, true}},
+		{"src/**/test.go", `C:\root\src\a\b\test.go`, [2]bool{false, true}},
+		{"src/*.go", `C:\root\src\a\main.go`, [2]bool{false, false}},
+		{"/src/", `C:\root\src\main.go`, [2]bool{false, true}},
+		{"*main.go", `C:\root\src\main.go`, [2]bool{true, true}},
+		{"main.go", `C:\root\src\main.go`, [2]bool{false, true}},
+		{"src/m?in.go", `C:\root\src\main.go`, [2]bool{false, true}},
+	}
+
+	for i, sep := range []string{"/", `\`} {
+		pathSeparator = sep
+
+		for _, tt := range tests {
+			got := NewTemplate(tt.template).Match(tt.str)
+			if got != tt.want[i] {
+				t.Errorf(
+					"separator %q: NewTemplate(%q).Match(%q) = %v, want %v",
+					sep, tt.template, tt.str, got, tt.want[i],
+				)
+			}
+		}
+	}
+}
+
+func TestTemplates_builder(t *testing.T) {
+	var ts Templates
+
+	ts.Add("*.go")
+	ts.Add("*.md")
+	ts.AddTemplate(ByExtension("txt"))
+
+	for str, want := range map[string]bool{
+		"main.go":   true,
+		"README.md": true,
+		"notes.txt": true,
+		"image.png": false,
+	} {
+		if got := ts.MatchAny(str); got != want {
+			t.Errorf("MatchAny(%q) = %t, want %t", str, got, want)
+		}
+	}
+
+	if !ts.Remove("*.md") {
+		t.Error("*.md was not removed")
+	}
+
+	if ts.Remove("*.md") {
+		t.Error("*.md was removed twice")
+	}
+
+	if len(ts) != 2 || ts.MatchAny("README.md") {
+		t.Errorf("unexpected templates after removal: %v", ts)
+	}
+}
+
+func TestTemplates_FilterReader(t *testing.T) {
+	ts := NewTemplates([]string{"*.go", "main*"})
+
+	in := "main.go\r\nutil.go\n\nmain.md\nREADME.md\nsrc/main_test.go\n"
+
+	var sb strings.Builder
+	if err := ts.FilterReader(strings.NewReader(in), &sb, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "main.go\nutil.go\nmain.md\nsrc/main_test.go\n"; sb.String() != want {
+		t.Errorf("got %q, want %q", sb.String(), want)
+	}
+
+	sb.Reset()
+
+	if err := ts.FilterReader(strings.NewReader(in), &sb, MatchAll); err != nil {
+		t.Fatal(err)
+	}
+
+	// Templates without separator match the last element of the path.
+	if want := "main.go\nsrc/main_test.go\n"; sb.String() != want {
+		t.Errorf("got %q, want %q", sb.String(), want)
+	}
+}
+
+func TestTemplatesFromFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "patterns")
+
+	content := "# Go sources\n*.go\n\n  *.md  \n\t# indented comment\n\\#notes\n"
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := TemplatesFromFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ts) != 3 {
+		t.Fatalf("expected 3 templates, got %d: %v", len(ts), ts)
+	}
+
+	for str, want := range map[string]bool{
+		"main.go":   true,
+		"README.md": true,
+		"#notes":    true,
+		"notes":     false,
+	} {
+		if got := ts.MatchAny(str); got != want {
+			t.Errorf("MatchAny(%q) = %t, want %t", str, got, want)
+		}
+	}
+
+	if err := os.WriteFile(name, []byte("*.go\n(a|b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := TemplatesFromFile(name); !errors.Is(err, ErrTemplateSyntax) {
+		t.Errorf("want %v, got: %v", ErrTemplateSyntax, err)
+	}
+
+	_, err = TemplatesFromFile(filepath.Join(dir, "missing"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("want %v, got: %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestTemplates_Dedup(t *testing.T) {
+	// Regexp has the same string form as the parsed template.
+	re, err := NewRegexpTemplate(`.go`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := NewTemplates([]string{"*.go", "*.md", "*.go", "*.{go,md}", "*.{go,md}"})
+	ts = append(ts, NewTemplate("*.md").Insensitive(), re, NewTemplate(".go"))
+
+	got := ts.Dedup()
+
+	want := []string{"*.go", "*.md", "*.{go,md}", "*.md", ".go", ".go"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d templates, want %d: %v", len(got), len(want), got)
+	}
+
+	for i, tmpl := range got {
+		if tmpl.String() != want[i] {
+			t.Errorf("template %d: got %q, want %q", i, tmpl, want[i])
+		}
+	}
+
+	if got[0] != ts[0] {
+		t.Error("first of the equal templates should be kept")
+	}
+
+	// Templates with the same elements, but different grouping differ.
+	a := NewTemplate("a").And(NewTemplate("x|y"))
+	b := NewTemplate("a&x|y")
+
+	if a.Match("y") == b.Match("y") {
+		t.Fatal("templates should match differently")
+	}
+
+	if got := (Templates{a, b}).Dedup(); len(got) != 2 {
+		t.Errorf("got %d templates, want 2: %v", len(got), got)
+	}
+}
+
+func TestTemplate_Validate(t *testing.T) {
+	for _, str := range []string{"", "!", "a|", "&b", "a||b", "()", "(a|)", "{,a}", "!()"} {
+		if err := NewTemplate(str).Validate(); !errors.Is(err, ErrTemplateSyntax) {
+			t.Errorf("%q: want %v, got: %v", str, ErrTemplateSyntax, err)
+		}
+	}
+
+	for _, str := range []string{"*", "**", "!*", "a|b", "a&!b", "(a|b)&c", "*.{go,md}", "/src/", "a?c"} {
+		if err := NewTemplate(str).Validate(); err != nil {
+			t.Errorf("%q: unexpected error: %v", str, err)
+		}
+	}
+
+	re, err := NewRegexpTemplate(`^a`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := re.Or(NewTemplate("")).Validate(); !errors.Is(err, ErrTemplateSyntax) {
+		t.Errorf("want %v, got: %v", ErrTemplateSyntax, err)
+	}
+
+	var nilTemplate *Template
+	if err := nilTemplate.Validate(); !errors.Is(err, ErrTemplateSyntax) {
+		t.Errorf("want %v, got: %v", ErrTemplateSyntax, err)
+	}
+}
+
+func TestAndOr(t *testing.T) {
+	a, b, c := NewTemplate("a*"), NewTemplate("*b"), NewTemplate("c")
+
+	tests := []struct {
+		name string
+		t    *Template
+		want map[string]bool
+	}{
+		{
+			name: "(a&b)|c",
+			t:    Or(And(a, b), c),
+			want: map[string]bool{"ab": true, "axb": true, "c": true, "a": false, "b": false},
+		},
+		{
+			name: "a&(b|c)",
+			t:    And(a, Or(b, c)),
+			want: map[string]bool{"ab": true, "ac": false, "b": false, "c": false},
+		},
+		{
+			name: "(x|y)&z keeps the chain of the operand",
+			t:    And(NewTemplate("x*|y*"), NewTemplate("*z")),
+			want: map[string]bool{"xz": true, "yz": true, "y": false, "x": false},
+		},
+		{
+			name: "single",
+			t:    Or(c),
+			want: map[string]bool{"c": true, "a": false},
+		},
+		{
+			name: "nested",
+			t:    Or(And(a, Or(b, NewTemplate("*d"))), And(c, NewTemplate("!a*"))),
+			want: map[string]bool{"ab": true, "ad": true, "ac": false, "c": true},
+		},
+	}
+
+	for _, tt := range tests {
+		for str, want := range tt.want {
+			if got := tt.t.Match(str); got != want {
+				t.Errorf("%s: Match(%q) = %t, want %t", tt.name, str, got, want)
+			}
+		}
+	}
+}
+
+func TestTemplate_nested(t *testing.T) {
+	tests := []struct {
+		name string
+		t    *Template
+		want map[string]bool
+	}{
+		{
+			name: "*project*&*main.go*",
+			t:    NewTemplate("*project*&*main.go*"),
+			want: map[string]bool{
+				"project/main.go":        true,
+				"a/my-project/b/main.go": true,
+				"project-main.go":        false,
+				"main.go/project":        false,
+				"project":                false,
+			},
+		},
+		{
+			name: "a&b&c keeps the order",
+			t:    NewTemplate("a&b&c"),
+			want: map[string]bool{"a/b/c": true, "a/x/b/y/c": true, "b/a/c": false, "a/c": false},
+		},
+		{
+			name: "And builder",
+			t:    And(NewTemplate("a"), NewTemplate("b")),
+			want: map[string]bool{"a/b": true, "x/a/b": true, "b": false},
+		},
+		{
+			name: "without chain",
+			t:    NewTemplate("b|c"),
+			want: map[string]bool{"a/b": true, "c": true, "b/a": false},
+		},
+	}
+
+	for _, tt := range tests {
+		n := *tt.t
+		n.nest = &nesting{}
+
+		for str, want := range tt.want {
+			if got := n.Match(str); got != want {
+				t.Errorf("%s: Match(%q) = %t, want %t", tt.name, str, got, want)
+			}
+		}
+	}
+}